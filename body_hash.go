@@ -0,0 +1,118 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// defaultBodyHashStoreSize the max entries of default body hash store
+	defaultBodyHashStoreSize = 1024
+)
+
+type (
+	// BodyHasher the function to compute the hash of body
+	BodyHasher func([]byte) string
+	// UnchangedListener the listener of unchanged response body
+	UnchangedListener func(*Dusk)
+
+	// BodyHashStore the store of body hash, the key is method and canonical url
+	BodyHashStore interface {
+		Get(key string) (hash string, ok bool)
+		Set(key, hash string)
+	}
+
+	memoryBodyHashStore struct {
+		cache *lru[string, string]
+	}
+)
+
+// SHA256BodyHasher compute the sha256 hash of body
+func SHA256BodyHasher(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewMemoryBodyHashStore create a memory store of body hash,
+// the least recently used entry will be removed if the entries are
+// more than max entries.
+func NewMemoryBodyHashStore(maxEntries int) BodyHashStore {
+	return &memoryBodyHashStore{
+		cache: newLRU[string, string](maxEntries),
+	}
+}
+
+func (s *memoryBodyHashStore) Get(key string) (hash string, ok bool) {
+	return s.cache.Get(key)
+}
+
+func (s *memoryBodyHashStore) Set(key, hash string) {
+	s.cache.Set(key, hash)
+}
+
+// SetBodyHasher set the hasher of body, default is sha256
+func (d *Dusk) SetBodyHasher(fn BodyHasher) *Dusk {
+	d.bodyHasher = fn
+	return d
+}
+
+// BodyHash get the hash of response body
+func (d *Dusk) BodyHash() string {
+	fn := d.bodyHasher
+	if fn == nil {
+		fn = SHA256BodyHasher
+	}
+	return fn(d.Body)
+}
+
+// IsUnchanged check the response body is the same as previous,
+// it's only available when the unchanged detector of instance is set.
+func (d *Dusk) IsUnchanged() bool {
+	return d.unchanged
+}
+
+// canonicalURL get the canonical url of request,
+// the scheme and host are lower case and the query is sorted.
+func canonicalURL(u *url.URL) string {
+	cu := *u
+	cu.Scheme = strings.ToLower(cu.Scheme)
+	cu.Host = strings.ToLower(cu.Host)
+	cu.RawQuery = cu.Query().Encode()
+	cu.Fragment = ""
+	return cu.String()
+}
+
+// newUnchangedDetector create a response listener which compares the hash
+// of body with the previous one in store
+func newUnchangedDetector(store BodyHashStore, fn UnchangedListener) ResponseListener {
+	return func(_ *http.Response, d *Dusk) error {
+		key := d.GetMethod() + " " + canonicalURL(d.Request.URL)
+		hash := d.BodyHash()
+		prev, ok := store.Get(key)
+		store.Set(key, hash)
+		if ok && prev == hash {
+			d.unchanged = true
+			if fn != nil {
+				fn(d)
+			}
+		}
+		return nil
+	}
+}
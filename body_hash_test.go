@@ -0,0 +1,100 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBodyHashStore(t *testing.T) {
+	assert := assert.New(t)
+	store := NewMemoryBodyHashStore(2)
+	store.Set("a", "1")
+	store.Set("b", "2")
+	// 访问 a 之后，b 为最久未使用
+	hash, ok := store.Get("a")
+	assert.True(ok)
+	assert.Equal("1", hash)
+	store.Set("c", "3")
+	_, ok = store.Get("b")
+	assert.False(ok)
+	hash, ok = store.Get("c")
+	assert.True(ok)
+	assert.Equal("3", hash)
+
+	store.Set("a", "4")
+	hash, _ = store.Get("a")
+	assert.Equal("4", hash)
+}
+
+func TestBodyHash(t *testing.T) {
+	assert := assert.New(t)
+	d := Get("/")
+	d.Body = []byte("abc")
+	assert.Equal("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", d.BodyHash())
+	d.SetBodyHasher(func(buf []byte) string {
+		return string(buf)
+	})
+	assert.Equal("abc", d.BodyHash())
+}
+
+func TestCanonicalURL(t *testing.T) {
+	assert := assert.New(t)
+	u, _ := url.Parse("HTTPS://Aslant.Site/Users?b=2&a=1#top")
+	assert.Equal("https://aslant.site/Users?a=1&b=2", canonicalURL(u))
+}
+
+func TestOnUnchanged(t *testing.T) {
+	assert := assert.New(t)
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := atomic.AddInt32(&count, 1)
+		if v >= 3 {
+			w.Write([]byte("changed"))
+			return
+		}
+		w.Write([]byte("data"))
+	}))
+	defer ts.Close()
+
+	unchangedCount := 0
+	ins := NewInstance().OnUnchanged(func(_ *Dusk) {
+		unchangedCount++
+	})
+
+	d := ins.Get(ts.URL + "/?b=2&a=1")
+	_, _, err := d.Do()
+	assert.Nil(err)
+	assert.False(d.IsUnchanged())
+
+	// query 顺序不同但 url 相同
+	d = ins.Get(ts.URL + "/?a=1&b=2")
+	_, _, err = d.Do()
+	assert.Nil(err)
+	assert.True(d.IsUnchanged())
+	assert.Equal(1, unchangedCount)
+
+	d = ins.Get(ts.URL + "/?a=1&b=2")
+	_, _, err = d.Do()
+	assert.Nil(err)
+	assert.False(d.IsUnchanged())
+	assert.Equal(1, unchangedCount)
+}
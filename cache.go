@@ -0,0 +1,323 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type (
+	// cacheEntry 仅保存响应的状态码、header 与数据，不引用响应及请求
+	cacheEntry struct {
+		status     string
+		statusCode int
+		header     http.Header
+		body       []byte
+		createdAt  time.Time
+		expiredAt  time.Time
+		// staleUntil 过期后仍可在请求出错时使用的截止时间
+		staleUntil time.Time
+	}
+)
+
+const (
+	// StaleServedValue the value key of stale response served
+	StaleServedValue = "staleServed"
+	// HeaderAge age
+	HeaderAge = "Age"
+	// HeaderAuthorization authorization
+	HeaderAuthorization = "Authorization"
+	// HeaderCookie cookie
+	HeaderCookie = "Cookie"
+
+	// defaultRequestCacheSize the default max entries of request cache
+	defaultRequestCacheSize = 1024
+)
+
+var (
+	// requestCache the cache of response, key: method+url hash
+	requestCache = newLRU[string, *cacheEntry](defaultRequestCacheSize)
+)
+
+// ClearRequestCache clear all cached response
+func ClearRequestCache() {
+	requestCache.Clear()
+}
+
+// SetRequestCacheSize set the max entries of request cache(default 1024),
+// the least recently used response will be removed if the entries are
+// more than max entries, zero means unlimited.
+func SetRequestCacheSize(maxEntries int) {
+	requestCache.SetMaxEntries(maxEntries)
+}
+
+const (
+	// cacheKeyValue the value key of custom cache key
+	cacheKeyValue = "cacheKey"
+)
+
+// SetCacheKey set the custom cache key of request,
+// the request of any method is cacheable if the cache key is set,
+// e.g. the post request of graphql.
+func (d *Dusk) SetCacheKey(key string) *Dusk {
+	return d.SetValue(cacheKeyValue, key)
+}
+
+// SetCacheKeyFromBody set the cache key as sha256(method+url+body),
+// it will be computed when the request is created. The request isn't
+// cached if the body can't be buffered, e.g. the reader larger than 1MB
+// or json stream.
+func (d *Dusk) SetCacheKeyFromBody() *Dusk {
+	d.cacheKeyFromBody = true
+	return d
+}
+
+// getCustomCacheKey get the custom cache key
+func (d *Dusk) getCustomCacheKey() string {
+	key, _ := d.GetValue(cacheKeyValue).(string)
+	return key
+}
+
+// getCacheCredentials get the authorization and cookies of request,
+// they are part of the cache key to avoid sharing response between users.
+// The header of request is used if it's created, it's final after
+// the request before listeners (including the listeners of instance).
+func (d *Dusk) getCacheCredentials() string {
+	header := d.header
+	if d.Request != nil {
+		header = d.Request.Header
+	}
+	credentials := header.Get(HeaderAuthorization)
+	if cookie := header.Get(HeaderCookie); cookie != "" {
+		credentials += " " + cookie
+	}
+	// 请求未创建时 cookie 未添加至请求头
+	if d.Request == nil {
+		for _, c := range d.cookies {
+			credentials += " " + c.String()
+		}
+	}
+	return credentials
+}
+
+// setCacheKeyFromBody set the cache key by the body of request,
+// the credentials are added by getCacheKey.
+func (d *Dusk) setCacheKeyFromBody(body []byte) {
+	h := sha256.New()
+	h.Write([]byte(d.method + " " + d.GetURL() + " "))
+	h.Write(body)
+	d.SetCacheKey(hex.EncodeToString(h.Sum(nil)))
+}
+
+func (d *Dusk) getCacheKey() string {
+	key := d.getCustomCacheKey()
+	// 自定义的 cache key 由调用者保证唯一
+	if key != "" && !d.cacheKeyFromBody {
+		return key
+	}
+	str := key
+	if str == "" {
+		str = d.method + " " + d.GetURL()
+	}
+	if credentials := d.getCacheCredentials(); credentials != "" {
+		str += " " + credentials
+	}
+	sum := sha256.Sum256([]byte(str))
+	return hex.EncodeToString(sum[:])
+}
+
+func (d *Dusk) isCacheable() bool {
+	if (d.cacheTTL <= 0 && d.maxStale <= 0) || d.isPipeMode() || d.isStreamMode() {
+		return false
+	}
+	// cookie jar 的 cookie 在请求时才添加，无法作为缓存的 key
+	if d.cookieJar != nil {
+		return false
+	}
+	key := d.getCustomCacheKey()
+	// 无法根据数据生成 cache key 的不缓存
+	if d.cacheKeyFromBody && key == "" {
+		return false
+	}
+	return d.method == http.MethodGet || key != ""
+}
+
+// ExpireAfter cache the response of get request for ttl,
+// the same request will get the response from cache before expired.
+// The Cache-Control(max-age, no-cache, no-store) and Expires of response
+// are respected, the ttl is used as the fallback and ceiling.
+// The Authorization and Cookie of request are part of the cache key,
+// and the request with cookie jar isn't cached. The cache is looked up
+// after the request before listeners, so the header set by them is used.
+func (d *Dusk) ExpireAfter(ttl time.Duration) *Dusk {
+	d.cacheTTL = ttl
+	return d
+}
+
+// getFromCache get the response from cache,
+// it returns false if the cache is not found or expired.
+func (d *Dusk) getFromCache() bool {
+	if d.cacheTTL <= 0 || !d.isCacheable() {
+		return false
+	}
+	key := d.getCacheKey()
+	entry, ok := requestCache.Get(key)
+	if !ok {
+		return false
+	}
+	now := d.getClock().Now()
+	if !now.Before(entry.expiredAt) {
+		// 仍可作为过期数据使用的不删除
+		if now.After(entry.staleUntil) {
+			requestCache.Delete(key)
+		}
+		return false
+	}
+	d.useCacheEntry(entry)
+	return true
+}
+
+// useCacheEntry set the response and body of dusk from cache entry
+func (d *Dusk) useCacheEntry(entry *cacheEntry) {
+	// 复制 header 与数据，避免调用方修改缓存中的数据
+	body := append([]byte(nil), entry.body...)
+	d.Response = &http.Response{
+		Status:        entry.status,
+		StatusCode:    entry.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       d.Request,
+	}
+	d.Body = body
+}
+
+// saveToCache save the success response to cache
+func (d *Dusk) saveToCache() {
+	if !d.isCacheable() || d.Response == nil {
+		return
+	}
+	resp := d.Response
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return
+	}
+	now := d.getClock().Now()
+	// 响应的缓存控制优先，ttl 仅作为未指定时的默认值及上限
+	ttl, ok := getCacheFreshness(resp.Header, now).ttl(d.cacheTTL)
+	if !ok {
+		return
+	}
+	expiredAt := now.Add(ttl)
+	// 仅缓存状态码、header 与数据，避免 dusk 被回收至 pool 后仍被缓存引用
+	requestCache.Set(d.getCacheKey(), &cacheEntry{
+		status:     resp.Status,
+		statusCode: resp.StatusCode,
+		header:     resp.Header.Clone(),
+		body:       d.Body,
+		createdAt:  now,
+		expiredAt:  expiredAt,
+		staleUntil: expiredAt.Add(d.maxStale),
+	})
+}
+
+// StaleIfError serve the stale response from cache if the request fails
+// (5xx or timeout) and the stale response is not older than max stale after
+// expired. Only the idempotent request qualifies, the successful response
+// will be cached for max stale even if ExpireAfter isn't set.
+// The stale response is marked by the value of StaleServedValue and Age
+// header, and the original error can be got by GetStaleError.
+func (d *Dusk) StaleIfError(maxStale time.Duration) *Dusk {
+	d.maxStale = maxStale
+	return d
+}
+
+// GetStaleError get the original error when stale response is served
+func (d *Dusk) GetStaleError() error {
+	return d.staleErr
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet,
+		http.MethodHead,
+		http.MethodOptions,
+		http.MethodTrace,
+		http.MethodPut,
+		http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isStaleQualified check whether the failure is upstream 5xx or timeout,
+// the other errors(e.g. the errors of listeners) are returned directly
+func isStaleQualified(resp *http.Response, err error) bool {
+	if resp != nil && resp.StatusCode >= http.StatusInternalServerError {
+		return true
+	}
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// serveStaleIfError serve the stale response if the request fails,
+// it returns true if the stale response is served.
+func (d *Dusk) serveStaleIfError(err error) bool {
+	if d.maxStale <= 0 || !isIdempotentMethod(d.method) || !d.isCacheable() {
+		return false
+	}
+	resp := d.Response
+	if !isStaleQualified(resp, err) {
+		return false
+	}
+	if err == nil {
+		err = &ResponseError{
+			StatusCode: resp.StatusCode,
+			Body:       d.Body,
+			Method:     d.method,
+			URL:        d.Request.URL.String(),
+		}
+	}
+	entry, ok := requestCache.Get(d.getCacheKey())
+	if !ok {
+		return false
+	}
+	now := d.getClock().Now()
+	if now.After(entry.staleUntil) {
+		return false
+	}
+	d.staleErr = err
+	d.useCacheEntry(entry)
+	d.Response.Header.Set(HeaderAge, strconv.Itoa(int(now.Sub(entry.createdAt).Seconds())))
+	d.SetValue(StaleServedValue, true)
+	return true
+}
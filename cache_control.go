@@ -0,0 +1,151 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderExpires expires
+	HeaderExpires = "Expires"
+	// HeaderDate date
+	HeaderDate = "Date"
+)
+
+type (
+	// cacheControl the directives of Cache-Control
+	cacheControl struct {
+		noStore   bool
+		noCache   bool
+		private   bool
+		hasMaxAge bool
+		maxAge    time.Duration
+	}
+	// cacheFreshness the freshness of response(RFC 9111 for common directives)
+	cacheFreshness struct {
+		noStore bool
+		noCache bool
+		// private the response is only for one user, the shared cache shouldn't store it
+		private bool
+		// explicit whether the lifetime is set by max-age or Expires
+		explicit bool
+		lifetime time.Duration
+		age      time.Duration
+	}
+)
+
+// parseDeltaSeconds parse the delta seconds of directive,
+// the invalid or negative value is treated as 0
+func parseDeltaSeconds(value string) time.Duration {
+	seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// parseCacheControl parse the directives of Cache-Control
+func parseCacheControl(value string) cacheControl {
+	cc := cacheControl{}
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.TrimSpace(directive)
+		name := directive
+		arg := ""
+		if index := strings.Index(directive, "="); index != -1 {
+			name = directive[:index]
+			arg = directive[index+1:]
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "no-store":
+			cc.noStore = true
+		case "no-cache":
+			cc.noCache = true
+		case "private":
+			cc.private = true
+		case "max-age":
+			cc.hasMaxAge = true
+			cc.maxAge = parseDeltaSeconds(arg)
+		}
+	}
+	return cc
+}
+
+// getCacheFreshness get the freshness of response at now(the time of receiving),
+// the lifetime is max-age or Expires - Date, and the age is the max of
+// Age header and now - Date
+func getCacheFreshness(header http.Header, now time.Time) cacheFreshness {
+	cc := parseCacheControl(strings.Join(header.Values(HeaderCacheControl), ","))
+	f := cacheFreshness{
+		noStore: cc.noStore,
+		noCache: cc.noCache,
+		private: cc.private,
+	}
+	date, err := http.ParseTime(header.Get(HeaderDate))
+	hasDate := err == nil
+	switch {
+	case cc.hasMaxAge:
+		f.explicit = true
+		f.lifetime = cc.maxAge
+	case header.Get(HeaderExpires) != "":
+		f.explicit = true
+		expires, err := http.ParseTime(header.Get(HeaderExpires))
+		// 无效的 Expires（如 0）表示已过期
+		if err != nil {
+			break
+		}
+		base := now
+		if hasDate {
+			base = date
+		}
+		if expires.After(base) {
+			f.lifetime = expires.Sub(base)
+		}
+	}
+	if hasDate && now.After(date) {
+		f.age = now.Sub(date)
+	}
+	if age := parseDeltaSeconds(header.Get(HeaderAge)); age > f.age {
+		f.age = age
+	}
+	return f
+}
+
+// ttl get the ttl of response, the fallback is used if the lifetime isn't
+// explicit, otherwise it's the ceiling. It returns false if the response
+// shouldn't be stored(no-store), and the ttl of no-cache is 0,
+// so it's always revalidated.
+func (f cacheFreshness) ttl(fallback time.Duration) (time.Duration, bool) {
+	if f.noStore {
+		return 0, false
+	}
+	if f.noCache {
+		return 0, true
+	}
+	if !f.explicit {
+		return fallback, true
+	}
+	ttl := f.lifetime - f.age
+	if ttl < 0 {
+		ttl = 0
+	}
+	if fallback > 0 && ttl > fallback {
+		ttl = fallback
+	}
+	return ttl, true
+}
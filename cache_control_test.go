@@ -0,0 +1,295 @@
+package dusk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	tests := []struct {
+		value string
+		cc    cacheControl
+	}{
+		{
+			value: "",
+			cc:    cacheControl{},
+		},
+		{
+			value: "public, max-age=60",
+			cc: cacheControl{
+				hasMaxAge: true,
+				maxAge:    time.Minute,
+			},
+		},
+		{
+			value: `max-age="30", s-maxage=120`,
+			cc: cacheControl{
+				hasMaxAge: true,
+				maxAge:    30 * time.Second,
+			},
+		},
+		{
+			value: "max-age=abc",
+			cc: cacheControl{
+				hasMaxAge: true,
+			},
+		},
+		{
+			value: "No-Cache",
+			cc: cacheControl{
+				noCache: true,
+			},
+		},
+		{
+			value: "no-store",
+			cc: cacheControl{
+				noStore: true,
+			},
+		},
+		{
+			value: "private, max-age=10",
+			cc: cacheControl{
+				private:   true,
+				hasMaxAge: true,
+				maxAge:    10 * time.Second,
+			},
+		},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.cc, parseCacheControl(tt.value), tt.value)
+	}
+}
+
+func TestCacheFreshnessTTL(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	formatTime := func(t time.Time) string {
+		return t.Format(http.TimeFormat)
+	}
+	tests := []struct {
+		name     string
+		header   http.Header
+		fallback time.Duration
+		ttl      time.Duration
+		stored   bool
+	}{
+		{
+			name:     "no directive uses fallback",
+			header:   http.Header{},
+			fallback: time.Minute,
+			ttl:      time.Minute,
+			stored:   true,
+		},
+		{
+			name: "max-age",
+			header: http.Header{
+				HeaderCacheControl: []string{"max-age=30"},
+			},
+			fallback: time.Minute,
+			ttl:      30 * time.Second,
+			stored:   true,
+		},
+		{
+			name: "fallback is ceiling",
+			header: http.Header{
+				HeaderCacheControl: []string{"max-age=3600"},
+			},
+			fallback: time.Minute,
+			ttl:      time.Minute,
+			stored:   true,
+		},
+		{
+			name: "max-age without fallback",
+			header: http.Header{
+				HeaderCacheControl: []string{"max-age=3600"},
+			},
+			ttl:    time.Hour,
+			stored: true,
+		},
+		{
+			name: "s-maxage is ignored",
+			header: http.Header{
+				HeaderCacheControl: []string{"s-maxage=30"},
+			},
+			fallback: time.Minute,
+			ttl:      time.Minute,
+			stored:   true,
+		},
+		{
+			name: "private is cacheable",
+			header: http.Header{
+				HeaderCacheControl: []string{"private, max-age=30"},
+			},
+			fallback: time.Minute,
+			ttl:      30 * time.Second,
+			stored:   true,
+		},
+		{
+			name: "no-cache is always revalidated",
+			header: http.Header{
+				HeaderCacheControl: []string{"no-cache, max-age=30"},
+			},
+			fallback: time.Minute,
+			ttl:      0,
+			stored:   true,
+		},
+		{
+			name: "no-store",
+			header: http.Header{
+				HeaderCacheControl: []string{"max-age=30, no-store"},
+			},
+			fallback: time.Minute,
+			stored:   false,
+		},
+		{
+			name: "max-age minus age",
+			header: http.Header{
+				HeaderCacheControl: []string{"max-age=30"},
+				HeaderAge:          []string{"10"},
+			},
+			fallback: time.Minute,
+			ttl:      20 * time.Second,
+			stored:   true,
+		},
+		{
+			name: "age greater than max-age",
+			header: http.Header{
+				HeaderCacheControl: []string{"max-age=30"},
+				HeaderAge:          []string{"40"},
+			},
+			fallback: time.Minute,
+			ttl:      0,
+			stored:   true,
+		},
+		{
+			name: "apparent age from date",
+			header: http.Header{
+				HeaderCacheControl: []string{"max-age=30"},
+				HeaderDate:         []string{formatTime(now.Add(-5 * time.Second))},
+				HeaderAge:          []string{"2"},
+			},
+			fallback: time.Minute,
+			ttl:      25 * time.Second,
+			stored:   true,
+		},
+		{
+			name: "age header greater than apparent age",
+			header: http.Header{
+				HeaderCacheControl: []string{"max-age=30"},
+				HeaderDate:         []string{formatTime(now.Add(-5 * time.Second))},
+				HeaderAge:          []string{"8"},
+			},
+			fallback: time.Minute,
+			ttl:      22 * time.Second,
+			stored:   true,
+		},
+		{
+			name: "expires minus date",
+			header: http.Header{
+				HeaderDate:    []string{formatTime(now.Add(-10 * time.Second))},
+				HeaderExpires: []string{formatTime(now.Add(20 * time.Second))},
+			},
+			fallback: time.Minute,
+			ttl:      20 * time.Second,
+			stored:   true,
+		},
+		{
+			name: "expires without date",
+			header: http.Header{
+				HeaderExpires: []string{formatTime(now.Add(20 * time.Second))},
+			},
+			fallback: time.Minute,
+			ttl:      20 * time.Second,
+			stored:   true,
+		},
+		{
+			name: "max-age overrides expires",
+			header: http.Header{
+				HeaderCacheControl: []string{"max-age=10"},
+				HeaderExpires:      []string{formatTime(now.Add(20 * time.Second))},
+			},
+			fallback: time.Minute,
+			ttl:      10 * time.Second,
+			stored:   true,
+		},
+		{
+			name: "invalid expires is expired",
+			header: http.Header{
+				HeaderExpires: []string{"0"},
+			},
+			fallback: time.Minute,
+			ttl:      0,
+			stored:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			ttl, stored := getCacheFreshness(tt.header, now).ttl(tt.fallback)
+			assert.Equal(tt.stored, stored)
+			assert.Equal(tt.ttl, ttl)
+		})
+	}
+	assert.True(t, getCacheFreshness(http.Header{
+		HeaderCacheControl: []string{"private, max-age=30"},
+	}, now).private)
+}
+
+func TestExpireAfterCacheControl(t *testing.T) {
+	defer ClearRequestCache()
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := atomic.AddInt32(&count, 1)
+		w.Header().Set(HeaderCacheControl, r.URL.Query().Get("cc"))
+		w.Write([]byte(strconv.Itoa(int(v))))
+	}))
+	defer ts.Close()
+
+	assert := assert.New(t)
+	// no-store 与 no-cache 均需要重新请求
+	for _, cc := range []string{"no-store", "no-cache"} {
+		atomic.StoreInt32(&count, 0)
+		for i := 1; i <= 2; i++ {
+			_, body, err := Get(ts.URL + "?cc=" + cc).ExpireAfter(time.Minute).Do()
+			assert.Nil(err)
+			assert.Equal(strconv.Itoa(i), string(body))
+		}
+	}
+
+	atomic.StoreInt32(&count, 0)
+	for i := 0; i < 2; i++ {
+		_, body, err := Get(ts.URL + "?cc=max-age=60").ExpireAfter(time.Minute).Do()
+		assert.Nil(err)
+		assert.Equal("1", string(body))
+	}
+}
+
+func TestExpireAfterNoCacheAtExpiredTime(t *testing.T) {
+	defer ClearRequestCache()
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := atomic.AddInt32(&count, 1)
+		w.Header().Set(HeaderCacheControl, "no-cache")
+		w.Write([]byte(strconv.Itoa(int(v))))
+	}))
+	defer ts.Close()
+
+	assert := assert.New(t)
+	// 时间不变时，ttl 为 0 的缓存在过期时间点也不可使用
+	clock := &stepClock{
+		now: time.Now(),
+	}
+	for i := 1; i <= 2; i++ {
+		_, body, err := Get(ts.URL).
+			SetClock(clock).
+			ExpireAfter(time.Minute).
+			Do()
+		assert.Nil(err)
+		assert.Equal(strconv.Itoa(i), string(body))
+	}
+}
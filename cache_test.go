@@ -0,0 +1,335 @@
+package dusk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpireAfter(t *testing.T) {
+	defer ClearRequestCache()
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := atomic.AddInt32(&count, 1)
+		if r.URL.Path == "/error" {
+			w.WriteHeader(500)
+		}
+		w.Write([]byte(strconv.Itoa(int(v))))
+	}))
+	defer ts.Close()
+
+	t.Run("cache get request", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&count, 0)
+		for i := 0; i < 3; i++ {
+			resp, body, err := Get(ts.URL).ExpireAfter(50 * time.Millisecond).Do()
+			assert.Nil(err)
+			assert.Equal(200, resp.StatusCode)
+			assert.Equal("1", string(body))
+		}
+		time.Sleep(60 * time.Millisecond)
+		_, body, err := Get(ts.URL).ExpireAfter(time.Second).Do()
+		assert.Nil(err)
+		assert.Equal("2", string(body))
+
+		ClearRequestCache()
+		_, body, err = Get(ts.URL).ExpireAfter(time.Second).Do()
+		assert.Nil(err)
+		assert.Equal("3", string(body))
+
+		// 修改返回的数据不影响缓存
+		_, body, err = Get(ts.URL).ExpireAfter(time.Second).Do()
+		assert.Nil(err)
+		body[0] = 'x'
+		_, body, err = Get(ts.URL).ExpireAfter(time.Second).Do()
+		assert.Nil(err)
+		assert.Equal("3", string(body))
+	})
+
+	t.Run("not cache post and error response", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&count, 0)
+		for i := 1; i <= 2; i++ {
+			_, body, err := Post(ts.URL).ExpireAfter(time.Second).Do()
+			assert.Nil(err)
+			assert.Equal(strconv.Itoa(i), string(body))
+		}
+		for i := 3; i <= 4; i++ {
+			_, body, err := Get(ts.URL + "/error").ExpireAfter(time.Second).Do()
+			assert.Nil(err)
+			assert.Equal(strconv.Itoa(i), string(body))
+		}
+	})
+
+	t.Run("instance expire after", func(t *testing.T) {
+		assert := assert.New(t)
+		ClearRequestCache()
+		atomic.StoreInt32(&count, 0)
+		ins := NewInstance().ExpireAfter(time.Second)
+		for i := 0; i < 2; i++ {
+			_, body, err := ins.Get(ts.URL + "/instance").Do()
+			assert.Nil(err)
+			assert.Equal("1", string(body))
+		}
+	})
+}
+
+func TestCacheKey(t *testing.T) {
+	defer ClearRequestCache()
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := atomic.AddInt32(&count, 1)
+		w.Write([]byte(strconv.Itoa(int(v))))
+	}))
+	defer ts.Close()
+
+	t.Run("custom cache key", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&count, 0)
+		for _, path := range []string{"/a", "/b"} {
+			_, body, err := Get(ts.URL + path).
+				SetCacheKey("users").
+				ExpireAfter(time.Second).
+				Do()
+			assert.Nil(err)
+			assert.Equal("1", string(body))
+		}
+		ClearRequestCache()
+	})
+
+	t.Run("cache key from body", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&count, 0)
+		query := func(q string) string {
+			d := Post(ts.URL + "/graphql").
+				Send(map[string]string{
+					"query": q,
+				}).
+				SetCacheKeyFromBody().
+				ExpireAfter(time.Second)
+			_, body, err := d.Do()
+			assert.Nil(err)
+			assert.NotEmpty(d.GetValue(cacheKeyValue))
+			return string(body)
+		}
+		assert.Equal("1", query("{ users }"))
+		assert.Equal("1", query("{ users }"))
+		assert.Equal("2", query("{ books }"))
+		assert.Equal("2", query("{ books }"))
+
+		// 未设置 cache key 的 post 请求不缓存
+		_, body, err := Post(ts.URL + "/graphql").
+			ExpireAfter(time.Second).
+			Do()
+		assert.Nil(err)
+		assert.Equal("3", string(body))
+	})
+	t.Run("not cache large body", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&count, 0)
+		content := strings.Repeat("a", maxRewindableBodySize+1)
+		for i := 1; i <= 2; i++ {
+			d := Post(ts.URL + "/graphql").
+				Send(struct{ io.Reader }{strings.NewReader(content)}).
+				SetCacheKeyFromBody().
+				ExpireAfter(time.Second)
+			_, body, err := d.Do()
+			assert.Nil(err)
+			assert.Nil(d.GetValue(cacheKeyValue))
+			assert.Equal(strconv.Itoa(i), string(body))
+		}
+	})
+}
+
+func TestCacheCredentials(t *testing.T) {
+	defer ClearRequestCache()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.Header.Get(HeaderAuthorization)
+		if c, err := r.Cookie("session"); err == nil {
+			user += c.Value
+		}
+		w.Write([]byte(user))
+	}))
+	defer ts.Close()
+
+	t.Run("authorization and cookie", func(t *testing.T) {
+		assert := assert.New(t)
+		for _, user := range []string{"a", "b", "a"} {
+			_, body, err := Get(ts.URL).
+				Set(HeaderAuthorization, user).
+				ExpireAfter(time.Second).
+				Do()
+			assert.Nil(err)
+			assert.Equal(user, string(body))
+		}
+		for _, user := range []string{"c", "d"} {
+			_, body, err := Get(ts.URL).
+				AddCookie("session", user).
+				ExpireAfter(time.Second).
+				Do()
+			assert.Nil(err)
+			assert.Equal(user, string(body))
+		}
+	})
+
+	t.Run("credentials from instance listener", func(t *testing.T) {
+		assert := assert.New(t)
+		ClearRequestCache()
+		newInstance := func(token string) *Instance {
+			return NewInstance().
+				ExpireAfter(time.Second).
+				AddRequestListener(func(req *http.Request, _ *Dusk) error {
+					req.Header.Set(HeaderAuthorization, token)
+					return nil
+				}, EventTypeBefore)
+		}
+		a := newInstance("a")
+		b := newInstance("b")
+		for _, ins := range []*Instance{a, b, a, b} {
+			d := ins.Get(ts.URL)
+			_, body, err := d.Do()
+			assert.Nil(err)
+			assert.Equal(d.Request.Header.Get(HeaderAuthorization), string(body))
+		}
+		// 相同的用户使用缓存
+		assert.Equal(2, requestCache.Len())
+	})
+
+	t.Run("not cache with cookie jar", func(t *testing.T) {
+		assert := assert.New(t)
+		jar, _ := cookiejar.New(nil)
+		d := Get(ts.URL).
+			WithCookieJar(jar).
+			ExpireAfter(time.Second)
+		assert.False(d.isCacheable())
+	})
+}
+
+func TestRequestCacheSize(t *testing.T) {
+	assert := assert.New(t)
+	defer ClearRequestCache()
+	defer SetRequestCacheSize(defaultRequestCacheSize)
+	ClearRequestCache()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer ts.Close()
+
+	SetRequestCacheSize(2)
+	for _, path := range []string{"/a", "/b", "/c"} {
+		_, _, err := Get(ts.URL + path).ExpireAfter(time.Second).Do()
+		assert.Nil(err)
+	}
+	assert.Equal(2, requestCache.Len())
+	_, ok := requestCache.Get(Get(ts.URL + "/a").getCacheKey())
+	assert.False(ok)
+	_, ok = requestCache.Get(Get(ts.URL + "/c").getCacheKey())
+	assert.True(ok)
+
+	SetRequestCacheSize(1)
+	assert.Equal(1, requestCache.Len())
+}
+
+func TestStaleIfError(t *testing.T) {
+	defer ClearRequestCache()
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := atomic.AddInt32(&count, 1)
+		if v > 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		w.Write([]byte(strconv.Itoa(int(v))))
+	}))
+	defer ts.Close()
+
+	t.Run("serve stale response", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&count, 0)
+		_, body, err := Get(ts.URL).StaleIfError(time.Second).Do()
+		assert.Nil(err)
+		assert.Equal("1", string(body))
+
+		// 5xx 时使用过期的缓存
+		d := Get(ts.URL).StaleIfError(time.Second)
+		resp, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal("1", string(body))
+		assert.Equal("0", resp.Header.Get(HeaderAge))
+		assert.Equal(true, d.GetValue(StaleServedValue))
+		respErr, ok := d.GetStaleError().(*ResponseError)
+		assert.True(ok)
+		assert.Equal(http.StatusBadGateway, respErr.StatusCode)
+
+		// 超时出错时使用过期的缓存
+		d = Get(ts.URL).
+			SetClient(&http.Client{
+				Transport: roundTripperFunc(func(_ *http.Request) (*http.Response, error) {
+					return nil, context.DeadlineExceeded
+				}),
+			}).
+			StaleIfError(time.Second)
+		_, body, err = d.Do()
+		assert.Nil(err)
+		assert.Equal("1", string(body))
+		assert.True(errors.Is(d.GetStaleError(), context.DeadlineExceeded))
+
+		// 非超时的出错不使用过期的缓存
+		e := errors.New("connection refused")
+		d = Get(ts.URL).
+			SetClient(&http.Client{
+				Transport: roundTripperFunc(func(_ *http.Request) (*http.Response, error) {
+					return nil, e
+				}),
+			}).
+			StaleIfError(time.Second)
+		_, _, err = d.Do()
+		assert.True(errors.Is(err, e))
+		assert.Nil(d.GetValue(StaleServedValue))
+
+		// 监听函数的出错不使用过期的缓存
+		d = Get(ts.URL).
+			AddRequestListener(func(_ *http.Request, _ *Dusk) error {
+				return ErrCircuitOpen
+			}, EventTypeBefore).
+			StaleIfError(time.Second)
+		_, _, err = d.Do()
+		assert.Equal(ErrCircuitOpen, err)
+		assert.Nil(d.GetValue(StaleServedValue))
+
+		// 非幂等的请求不使用过期的缓存
+		d = Post(ts.URL).
+			SetCacheKey(Get(ts.URL).getCacheKey()).
+			StaleIfError(time.Second)
+		resp, _, err = d.Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusBadGateway, resp.StatusCode)
+		assert.Nil(d.GetValue(StaleServedValue))
+		ClearRequestCache()
+	})
+
+	t.Run("stale response expired", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&count, 0)
+		_, _, err := Get(ts.URL).StaleIfError(10 * time.Millisecond).Do()
+		assert.Nil(err)
+		time.Sleep(20 * time.Millisecond)
+
+		d := Get(ts.URL).StaleIfError(10 * time.Millisecond)
+		resp, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusBadGateway, resp.StatusCode)
+		assert.Equal("2", string(body))
+		assert.Nil(d.GetValue(StaleServedValue))
+	})
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen the circuit breaker is open, the request is rejected
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+)
+
+type (
+	// CircuitBreaker circuit breaker for request
+	CircuitBreaker interface {
+		// Allow check the request is allowed
+		Allow() bool
+		// RecordSuccess record the request is success
+		RecordSuccess()
+		// RecordFailure record the request is failure
+		RecordFailure()
+	}
+
+	basicCircuitBreaker struct {
+		sync.Mutex
+		threshold int
+		window    time.Duration
+		failures  int
+		// firstFailure 窗口内第一次失败的时间
+		firstFailure time.Time
+		// openedAt 熔断开启的时间
+		openedAt time.Time
+		// trying 半开状态下是否已有试探请求
+		trying bool
+		// clock 添加至 instance 时使用其 clock，未设置则使用默认的 clock
+		clock Clock
+	}
+)
+
+// NewBasicCircuitBreaker create a circuit breaker, it opens when the failures
+// reach the threshold in the window, and the requests will be rejected for
+// the window. After that only one request is allowed as a trial, the breaker
+// closes if it succeeds, otherwise opens again.
+func NewBasicCircuitBreaker(threshold int, window time.Duration) CircuitBreaker {
+	return &basicCircuitBreaker{
+		threshold: threshold,
+		window:    window,
+	}
+}
+
+// setClock set the clock of circuit breaker
+func (cb *basicCircuitBreaker) setClock(c Clock) {
+	cb.Lock()
+	defer cb.Unlock()
+	cb.clock = c
+}
+
+// now get the current time from the clock of circuit breaker
+func (cb *basicCircuitBreaker) now() time.Time {
+	if cb.clock != nil {
+		return cb.clock.Now()
+	}
+	return GetClock().Now()
+}
+
+func (cb *basicCircuitBreaker) Allow() bool {
+	cb.Lock()
+	defer cb.Unlock()
+	if cb.openedAt.IsZero() {
+		return true
+	}
+	if cb.now().Sub(cb.openedAt) < cb.window || cb.trying {
+		return false
+	}
+	// 半开状态，允许一个试探请求
+	cb.trying = true
+	return true
+}
+
+func (cb *basicCircuitBreaker) RecordSuccess() {
+	cb.Lock()
+	defer cb.Unlock()
+	cb.failures = 0
+	cb.firstFailure = time.Time{}
+	cb.openedAt = time.Time{}
+	cb.trying = false
+}
+
+func (cb *basicCircuitBreaker) RecordFailure() {
+	cb.Lock()
+	defer cb.Unlock()
+	now := cb.now()
+	// 试探请求失败，重新开启熔断
+	if cb.trying {
+		cb.trying = false
+		cb.openedAt = now
+		return
+	}
+	if cb.firstFailure.IsZero() || now.Sub(cb.firstFailure) >= cb.window {
+		cb.firstFailure = now
+		cb.failures = 0
+	}
+	cb.failures++
+	if cb.failures >= cb.threshold {
+		cb.openedAt = now
+	}
+}
+
+// SetCircuitBreaker set the circuit breaker for all requests of instance,
+// the request will be rejected with ErrCircuitOpen if it isn't allowed.
+func (ins *Instance) SetCircuitBreaker(cb CircuitBreaker) *Instance {
+	ins.circuitBreaker = cb
+	return ins
+}
+
+func addCircuitBreaker(d *Dusk, cb CircuitBreaker) {
+	// 使用 instance 的 clock 统计失败与熔断的时间
+	if b, ok := cb.(*basicCircuitBreaker); ok && d.clock != nil {
+		b.setClock(d.clock)
+	}
+	d.AddRequestListener(func(_ *http.Request, d *Dusk) error {
+		// 预取的请求不统计，因此也不占用半开状态的试探请求
+		if d.IsPrefetch() {
+			return nil
+		}
+		if !cb.Allow() {
+			return ErrCircuitOpen
+		}
+		return nil
+	}, EventTypeBefore)
+	d.AddErrorListener(func(err error, _ *Dusk) error {
+		// 被熔断拒绝的请求不统计
+		if !errors.Is(err, ErrCircuitOpen) {
+			cb.RecordFailure()
+		}
+		return nil
+	})
+	d.AddDoneListener(func(d *Dusk) error {
+		if d.Err == nil && !d.IsPrefetch() {
+			cb.RecordSuccess()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubCircuitBreaker struct {
+	failures  int
+	successes int
+}
+
+func (cb *stubCircuitBreaker) Allow() bool {
+	return cb.failures < 3
+}
+
+func (cb *stubCircuitBreaker) RecordSuccess() {
+	cb.successes++
+}
+
+func (cb *stubCircuitBreaker) RecordFailure() {
+	cb.failures++
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+	count := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	cb := &stubCircuitBreaker{}
+	ins := NewInstance().SetCircuitBreaker(cb)
+	_, _, err := ins.Get(ts.URL).Do()
+	assert.Nil(err)
+	assert.Equal(1, cb.successes)
+
+	// 请求出错，3次后熔断
+	serverErr := errors.New("server error")
+	for i := 0; i < 3; i++ {
+		_, _, err = ins.Get(ts.URL).
+			AddResponseListener(func(_ *http.Response, _ *Dusk) error {
+				return serverErr
+			}, EventTypeAfter).
+			Do()
+		assert.Equal(serverErr, err)
+	}
+	assert.Equal(3, cb.failures)
+	assert.Equal(4, count)
+
+	_, _, err = ins.Get(ts.URL).Do()
+	assert.Equal(ErrCircuitOpen, err)
+	assert.Equal(4, count)
+	assert.Equal(3, cb.failures)
+	assert.Equal(1, cb.successes)
+}
+
+type stepClock struct {
+	realClock
+	now time.Time
+}
+
+func (c *stepClock) Now() time.Time {
+	return c.now
+}
+
+func TestBasicCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+	clock := &stepClock{
+		now: time.Now(),
+	}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	cb := NewBasicCircuitBreaker(3, time.Second)
+	assert.True(cb.Allow())
+	cb.RecordFailure()
+	cb.RecordFailure()
+	// 超过窗口时间，重新计数
+	clock.now = clock.now.Add(time.Second)
+	cb.RecordFailure()
+	cb.RecordFailure()
+	assert.True(cb.Allow())
+	cb.RecordFailure()
+	assert.False(cb.Allow())
+
+	// 熔断时间过后，只允许一个试探请求
+	clock.now = clock.now.Add(time.Second)
+	assert.True(cb.Allow())
+	assert.False(cb.Allow())
+	// 试探失败，重新熔断
+	cb.RecordFailure()
+	assert.False(cb.Allow())
+
+	clock.now = clock.now.Add(time.Second)
+	assert.True(cb.Allow())
+	cb.RecordSuccess()
+	assert.True(cb.Allow())
+	assert.True(cb.Allow())
+}
+
+func TestBasicCircuitBreakerClock(t *testing.T) {
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	clock := &stepClock{
+		now: time.Now(),
+	}
+	cb := NewBasicCircuitBreaker(1, time.Minute)
+	ins := NewInstance().
+		SetClock(clock).
+		SetCircuitBreaker(cb)
+	_, _, err := ins.Get(ts.URL).Expect2xx().Do()
+	assert.NotNil(err)
+	_, _, err = ins.Get(ts.URL).Do()
+	assert.Equal(ErrCircuitOpen, err)
+
+	// 使用 instance 的 clock 判断熔断时间
+	clock.now = clock.now.Add(time.Minute)
+	assert.True(cb.Allow())
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// Timer timer of clock
+	Timer interface {
+		// C the channel on which the time is delivered
+		C() <-chan time.Time
+		// Stop stop the timer
+		Stop() bool
+	}
+	// Clock the clock for all time based features,
+	// it can be replaced by fake clock for testing.
+	Clock interface {
+		// Now get the current time
+		Now() time.Time
+		// Sleep pause for duration, it returns the error of context if it's done
+		Sleep(ctx context.Context, d time.Duration) error
+		// NewTimer create a timer
+		NewTimer(d time.Duration) Timer
+	}
+
+	realClock struct{}
+	realTimer struct {
+		t *time.Timer
+	}
+)
+
+var (
+	defaultClock     Clock = realClock{}
+	defaultClockLock sync.RWMutex
+)
+
+func (rt *realTimer) C() <-chan time.Time {
+	return rt.t.C
+}
+
+func (rt *realTimer) Stop() bool {
+	return rt.t.Stop()
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{
+		t: time.NewTimer(d),
+	}
+}
+
+// SetClock set the default clock, if c is nil, the real clock will be used
+func SetClock(c Clock) {
+	defaultClockLock.Lock()
+	defer defaultClockLock.Unlock()
+	if c == nil {
+		c = realClock{}
+	}
+	defaultClock = c
+}
+
+// GetClock get the default clock
+func GetClock() Clock {
+	defaultClockLock.RLock()
+	defer defaultClockLock.RUnlock()
+	return defaultClock
+}
+
+// SetClock set the clock for the request
+func (d *Dusk) SetClock(c Clock) *Dusk {
+	d.clock = c
+	return d
+}
+
+// getClock get the clock of request, the default clock will be used if not set
+func (d *Dusk) getClock() Clock {
+	if d.clock != nil {
+		return d.clock
+	}
+	return GetClock()
+}
+
+// GetClock get the clock of request, the default clock will be used if not set,
+// the listeners measuring time should use it.
+func (d *Dusk) GetClock() Clock {
+	return d.getClock()
+}
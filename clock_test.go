@@ -0,0 +1,35 @@
+package dusk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealClock(t *testing.T) {
+	assert := assert.New(t)
+	c := GetClock()
+	assert.Equal(realClock{}, c)
+	assert.Nil(c.Sleep(context.Background(), time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(context.Canceled, c.Sleep(ctx, time.Second))
+	timer := c.NewTimer(time.Millisecond)
+	<-timer.C()
+	assert.False(timer.Stop())
+}
+
+func TestSetClock(t *testing.T) {
+	assert := assert.New(t)
+	defer SetClock(nil)
+	fake := &struct{ realClock }{}
+	SetClock(fake)
+	assert.Equal(fake, GetClock())
+	assert.Equal(fake, Get("/").getClock())
+	d := Get("/").SetClock(realClock{})
+	assert.Equal(realClock{}, d.getClock())
+	SetClock(nil)
+	assert.Equal(realClock{}, GetClock())
+}
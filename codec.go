@@ -0,0 +1,182 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	// MIMEApplicationXML application xml
+	MIMEApplicationXML = "application/xml"
+	// MIMETextXML text xml
+	MIMETextXML = "text/xml"
+)
+
+var (
+	// ErrCodecNotFound the codec of content type is not registered
+	ErrCodecNotFound = errors.New("codec is not found")
+	// ErrFormCodecType the type is not supported by form codec
+	ErrFormCodecType = errors.New("form codec only supports url.Values, map[string]string and map[string][]string")
+)
+
+type (
+	// Codec the codec to marshal request data and unmarshal response body
+	Codec interface {
+		Marshal(v interface{}) ([]byte, error)
+		Unmarshal(data []byte, v interface{}) error
+	}
+	jsonCodec struct{}
+	xmlCodec  struct{}
+	formCodec struct{}
+)
+
+var (
+	codecs = map[string]Codec{
+		MIMEApplicationJSON:           jsonCodec{},
+		MIMEApplicationXML:            xmlCodec{},
+		MIMETextXML:                   xmlCodec{},
+		MIMEApplicationFormUrlencoded: formCodec{},
+	}
+	codecsLock sync.RWMutex
+)
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+func (formCodec) Marshal(v interface{}) ([]byte, error) {
+	switch data := v.(type) {
+	case url.Values:
+		return []byte(data.Encode()), nil
+	case map[string][]string:
+		return []byte(url.Values(data).Encode()), nil
+	case map[string]string:
+		values := make(url.Values)
+		for key, value := range data {
+			values.Set(key, value)
+		}
+		return []byte(values.Encode()), nil
+	}
+	return nil, ErrFormCodecType
+}
+
+func (formCodec) Unmarshal(data []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	switch result := v.(type) {
+	case *url.Values:
+		*result = values
+	case *map[string][]string:
+		*result = values
+	case *map[string]string:
+		m := make(map[string]string)
+		for key := range values {
+			m[key] = values.Get(key)
+		}
+		*result = m
+	default:
+		return ErrFormCodecType
+	}
+	return nil
+}
+
+// RegisterCodec register the codec of content type, it's used by SendAs
+// to marshal request data and Into to unmarshal response body,
+// e.g. RegisterCodec("application/msgpack", msgpackCodec)
+func RegisterCodec(contentType string, codec Codec) {
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+	codecs[strings.ToLower(contentType)] = codec
+}
+
+// GetCodec get the codec of content type, the parameters of content type
+// are ignored, and the json codec is used for the type with +json suffix
+// (e.g. application/problem+json), the xml codec for +xml suffix.
+func GetCodec(contentType string) (Codec, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		mediaType = MIMEApplicationJSON
+	case strings.HasSuffix(mediaType, "+xml"):
+		mediaType = MIMEApplicationXML
+	}
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+	codec, ok := codecs[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrCodecNotFound, contentType)
+	}
+	return codec, nil
+}
+
+// SendAs set the send data and marshal it by the codec of content type,
+// the content type of request will be set as it if not set.
+func (d *Dusk) SendAs(contentType string, data interface{}) *Dusk {
+	d.data = data
+	d.sendContentType = contentType
+	return d
+}
+
+// Into set the value which the response body will be unmarshaled into,
+// the codec is selected by the Content-Type of response. It's only done for
+// the successful request, and the decode error won't be returned as the
+// error of request, it can be got by DecodeErr.
+func (d *Dusk) Into(v interface{}) *Dusk {
+	d.into = v
+	return d
+}
+
+// DecodeErr get the error of unmarshaling response body into the value of Into
+func (d *Dusk) DecodeErr() error {
+	return d.decodeErr
+}
+
+// decodeInto unmarshal the response body into the value of Into
+func (d *Dusk) decodeInto() {
+	if d.into == nil || d.Response == nil {
+		return
+	}
+	codec, err := GetCodec(d.Response.Header.Get(HeaderContentType))
+	if err != nil {
+		d.decodeErr = err
+		return
+	}
+	d.decodeErr = codec.Unmarshal(d.Body, d.into)
+}
@@ -0,0 +1,169 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type textCodec struct{}
+
+func (textCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(v.(string)), nil
+}
+
+func (textCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*string)) = string(data)
+	return nil
+}
+
+func TestGetCodec(t *testing.T) {
+	assert := assert.New(t)
+	codec, err := GetCodec("application/json; charset=utf-8")
+	assert.Nil(err)
+	assert.Equal(jsonCodec{}, codec)
+
+	codec, err = GetCodec("application/problem+json")
+	assert.Nil(err)
+	assert.Equal(jsonCodec{}, codec)
+
+	codec, err = GetCodec("Text/XML")
+	assert.Nil(err)
+	assert.Equal(xmlCodec{}, codec)
+
+	_, err = GetCodec("application/msgpack")
+	assert.True(errors.Is(err, ErrCodecNotFound))
+
+	RegisterCodec("text/x-test", textCodec{})
+	defer func() {
+		codecsLock.Lock()
+		delete(codecs, "text/x-test")
+		codecsLock.Unlock()
+	}()
+	codec, err = GetCodec("text/x-test")
+	assert.Nil(err)
+	assert.Equal(textCodec{}, codec)
+}
+
+func TestFormCodec(t *testing.T) {
+	assert := assert.New(t)
+	codec := formCodec{}
+	buf, err := codec.Marshal(map[string]string{
+		"a": "1",
+	})
+	assert.Nil(err)
+	assert.Equal("a=1", string(buf))
+	_, err = codec.Marshal(1)
+	assert.Equal(ErrFormCodecType, err)
+
+	m := make(map[string]string)
+	assert.Nil(codec.Unmarshal([]byte("a=1&b=2"), &m))
+	assert.Equal(map[string]string{
+		"a": "1",
+		"b": "2",
+	}, m)
+	values := make(url.Values)
+	assert.Nil(codec.Unmarshal([]byte("a=1&a=2"), &values))
+	assert.Equal([]string{"1", "2"}, values["a"])
+}
+
+type codecUser struct {
+	XMLName xml.Name `json:"-" xml:"user"`
+	Name    string   `json:"name" xml:"name"`
+}
+
+func TestSendAsAndInto(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set(HeaderContentType, r.Header.Get(HeaderContentType))
+		if r.URL.Path == "/text" {
+			w.Header().Set(HeaderContentType, "text/plain")
+		}
+		w.Write(buf)
+	}))
+	defer ts.Close()
+
+	t.Run("xml", func(t *testing.T) {
+		assert := assert.New(t)
+		result := codecUser{}
+		d := Post(ts.URL).
+			SendAs(MIMEApplicationXML, &codecUser{
+				Name: "tree.xie",
+			}).
+			Into(&result)
+		_, body, err := d.Do()
+		assert.Nil(err)
+		assert.Nil(d.DecodeErr())
+		assert.Equal("<user><name>tree.xie</name></user>", string(body))
+		assert.Equal(MIMEApplicationXML, d.Request.Header.Get(HeaderContentType))
+		assert.Equal("tree.xie", result.Name)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		assert := assert.New(t)
+		result := codecUser{}
+		d := Post(ts.URL).
+			Send(&codecUser{
+				Name: "tree.xie",
+			}).
+			Into(&result)
+		_, _, err := d.Do()
+		assert.Nil(err)
+		assert.Nil(d.DecodeErr())
+		assert.Equal("tree.xie", result.Name)
+	})
+
+	t.Run("codec not found", func(t *testing.T) {
+		assert := assert.New(t)
+		_, _, err := Post(ts.URL).
+			SendAs("application/msgpack", 1).
+			Do()
+		assert.True(errors.Is(err, ErrCodecNotFound))
+	})
+
+	t.Run("decode error", func(t *testing.T) {
+		assert := assert.New(t)
+		result := codecUser{}
+		d := Post(ts.URL + "/text").
+			Send(&codecUser{
+				Name: "tree.xie",
+			}).
+			Into(&result)
+		_, body, err := d.Do()
+		// 解析出错不影响请求结果
+		assert.Nil(err)
+		assert.Equal(`{"name":"tree.xie"}`, string(body))
+		assert.True(errors.Is(d.DecodeErr(), ErrCodecNotFound))
+
+		var m map[string]interface{}
+		d = Post(ts.URL).
+			Type(MIMEApplicationJSON).
+			Send("abc").
+			Into(&m)
+		_, _, err = d.Do()
+		assert.Nil(err)
+		_, ok := d.DecodeErr().(*json.UnmarshalTypeError)
+		assert.True(ok)
+	})
+}
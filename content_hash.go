@@ -0,0 +1,109 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	// HeaderContentMD5 content md5 header
+	HeaderContentMD5 = "Content-MD5"
+	// HeaderDigest digest header
+	HeaderDigest = "Digest"
+
+	// HashMD5 md5 hash algorithm
+	HashMD5 = "md5"
+	// HashSHA1 sha1 hash algorithm
+	HashSHA1 = "sha1"
+	// HashSHA256 sha256 hash algorithm
+	HashSHA256 = "sha256"
+)
+
+var (
+	// ErrHashAlgorithmInvalid the hash algorithm is not supported
+	ErrHashAlgorithmInvalid = errors.New("hash algorithm should be md5, sha1 or sha256")
+)
+
+// newContentHash get the hash and the header setter of algorithm
+func newContentHash(algo string) (func() hash.Hash, func(http.Header, string)) {
+	switch algo {
+	case HashMD5:
+		return md5.New, func(h http.Header, v string) {
+			h.Set(HeaderContentMD5, v)
+		}
+	case HashSHA1:
+		return sha1.New, func(h http.Header, v string) {
+			h.Set(HeaderDigest, "sha="+v)
+		}
+	case HashSHA256:
+		return sha256.New, func(h http.Header, v string) {
+			h.Set(HeaderDigest, "sha-256="+v)
+		}
+	}
+	return nil, nil
+}
+
+// getRequestBody get the body of request, the body will be restored
+// if it's read directly (GetBody is not set)
+func getRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		r, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	return buf, nil
+}
+
+// WithContentHash compute the hash of request body and set the header for
+// upload integrity, md5 sets Content-MD5 and sha1/sha256 set Digest
+// (sha=<base64> or sha-256=<base64>).
+func (d *Dusk) WithContentHash(algo string) *Dusk {
+	newHash, setHeader := newContentHash(algo)
+	if newHash == nil {
+		d.buildErr = ErrHashAlgorithmInvalid
+		return d
+	}
+	return d.AddRequestListener(func(req *http.Request, _ *Dusk) error {
+		buf, err := getRequestBody(req)
+		if err != nil {
+			return err
+		}
+		h := newHash()
+		h.Write(buf)
+		setHeader(req.Header, base64.StdEncoding.EncodeToString(h.Sum(nil)))
+		return nil
+	}, EventTypeBefore)
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithContentHash(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set(HeaderContentMD5, r.Header.Get(HeaderContentMD5))
+		w.Header().Set(HeaderDigest, r.Header.Get(HeaderDigest))
+		w.Write(buf)
+	}))
+	defer ts.Close()
+
+	data := map[string]string{
+		"name": "tree.xie",
+	}
+	// {"name":"tree.xie"}
+	tests := []struct {
+		algo   string
+		header string
+		value  string
+	}{
+		{HashMD5, HeaderContentMD5, "g6m8/uzxsVpGMtSkT59hnA=="},
+		{HashSHA1, HeaderDigest, "sha=yo9YroUOjW1obRvVoXfrCiL2JGE="},
+		{HashSHA256, HeaderDigest, "sha-256=mgBetmrvnePQyik2atrWBMl9gyQs4tyO7DF5B9QKan0="},
+	}
+	for _, tt := range tests {
+		assert := assert.New(t)
+		resp, body, err := Put(ts.URL).
+			Send(data).
+			WithContentHash(tt.algo).
+			Do()
+		assert.Nil(err)
+		assert.Equal(`{"name":"tree.xie"}`, string(body))
+		assert.Equal(tt.value, resp.Header.Get(tt.header), tt.algo)
+	}
+
+	t.Run("restore reader body", func(t *testing.T) {
+		assert := assert.New(t)
+		// 大于 1MB 的 reader 不会设置 GetBody
+		content := strings.Repeat("a", maxRewindableBodySize+1)
+		resp, body, err := Put(ts.URL).
+			Send(ioutil.NopCloser(strings.NewReader(content))).
+			WithContentHash(HashMD5).
+			Do()
+		assert.Nil(err)
+		assert.Equal(content, string(body))
+		assert.NotEmpty(resp.Header.Get(HeaderContentMD5))
+	})
+
+	t.Run("invalid algorithm", func(t *testing.T) {
+		_, _, err := Put(ts.URL).WithContentHash("crc32").Do()
+		assert.Equal(t, ErrHashAlgorithmInvalid, err)
+	})
+}
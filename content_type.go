@@ -0,0 +1,96 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const (
+	// contentTypePreviewSize the max size of body preview
+	contentTypePreviewSize = 512
+)
+
+type (
+	// UnexpectedContentTypeError the content type of response isn't expected
+	UnexpectedContentTypeError struct {
+		// Got the media type of response
+		Got string
+		// Want the expected media types
+		Want []string
+		// BodyPreview the beginning of response body(at most 512 bytes),
+		// it's empty if the body is encoded
+		BodyPreview string
+	}
+)
+
+func (e *UnexpectedContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected content type %q, want %s", e.Got, strings.Join(e.Want, ", "))
+}
+
+// getMediaType get the media type of content type(lower case without parameters)
+func getMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// 无法解析时，仅去除参数
+		mediaType = strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	}
+	return strings.ToLower(mediaType)
+}
+
+// matchMediaType check whether the media type matches the patterns,
+// the wildcard is supported, e.g. application/*+json
+func matchMediaType(mediaType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		matched, _ := path.Match(getMediaType(pattern), mediaType)
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireContentType require the media type of response(parameters ignored)
+// matches one of the types, e.g. application/json or application/*+json,
+// the *UnexpectedContentTypeError will be returned if not matched.
+// The response of 204 and 304 is not checked as it has no body.
+func (d *Dusk) RequireContentType(types ...string) *Dusk {
+	return d.AddResponseListener(func(resp *http.Response, _ *Dusk) error {
+		if resp.StatusCode == http.StatusNoContent ||
+			resp.StatusCode == http.StatusNotModified {
+			return nil
+		}
+		mediaType := getMediaType(resp.Header.Get(HeaderContentType))
+		if matchMediaType(mediaType, types) {
+			return nil
+		}
+		err := &UnexpectedContentTypeError{
+			Got:  mediaType,
+			Want: types,
+		}
+		// 响应数据未读取，读取部分用于排查问题（压缩的数据无意义，不读取）
+		if resp.Body != nil && resp.Header.Get(HeaderContentEncoding) == "" {
+			buf, _ := ioutil.ReadAll(io.LimitReader(resp.Body, contentTypePreviewSize))
+			err.BodyPreview = string(buf)
+		}
+		return err
+	}, EventTypeBefore)
+}
@@ -0,0 +1,71 @@
+package dusk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchMediaType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		patterns    []string
+		matched     bool
+	}{
+		{"application/json; charset=utf-8", []string{"application/json"}, true},
+		{"Application/JSON", []string{"application/json"}, true},
+		{"application/vnd.api+json", []string{"application/*+json"}, true},
+		{"application/problem+json", []string{"text/html", "application/*+json"}, true},
+		{"application/json", []string{"application/*"}, true},
+		{"text/html", []string{"*/*"}, true},
+		{"text/html; charset=utf-8", []string{"application/json", "application/*+json"}, false},
+		{"application/xml", []string{"application/*+json"}, false},
+		{"", []string{"application/json"}, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.matched, matchMediaType(getMediaType(tt.contentType), tt.patterns), tt.contentType)
+	}
+}
+
+func TestRequireContentType(t *testing.T) {
+	html := "<html>" + strings.Repeat("login", 200) + "</html>"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html":
+			w.Header().Set(HeaderContentType, "text/html; charset=utf-8")
+			w.Write([]byte(html))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set(HeaderContentType, "application/vnd.api+json; charset=utf-8")
+			w.Write([]byte(`{"name":"tree.xie"}`))
+		}
+	}))
+	defer ts.Close()
+
+	assert := assert.New(t)
+	_, body, err := Get(ts.URL).
+		RequireContentType(MIMEApplicationJSON, "application/*+json").
+		Do()
+	assert.Nil(err)
+	assert.Equal(`{"name":"tree.xie"}`, string(body))
+
+	_, _, err = Get(ts.URL + "/empty").
+		RequireContentType(MIMEApplicationJSON).
+		Do()
+	assert.Nil(err)
+
+	_, _, err = Get(ts.URL+"/html").
+		RequireContentType(MIMEApplicationJSON, "application/*+json").
+		Do()
+	ce := &UnexpectedContentTypeError{}
+	assert.True(errors.As(err, &ce))
+	assert.Equal("text/html", ce.Got)
+	assert.Equal([]string{MIMEApplicationJSON, "application/*+json"}, ce.Want)
+	assert.Equal(html[:contentTypePreviewSize], ce.BodyPreview)
+	assert.Equal(`unexpected content type "text/html", want application/json, application/*+json`, ce.Error())
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// NewDefaultCookieJar create a cookie jar of net/http/cookiejar
+// with the public suffix list.
+func NewDefaultCookieJar() http.CookieJar {
+	// cookiejar.New 不会返回出错
+	jar, _ := cookiejar.New(&cookiejar.Options{
+		PublicSuffixList: publicsuffix.List,
+	})
+	return jar
+}
+
+// WithCookieJar set the cookie jar of the request, the client is copied
+// for the request, so the shared client won't be modified.
+func (d *Dusk) WithCookieJar(jar http.CookieJar) *Dusk {
+	d.cookieJar = jar
+	return d
+}
+
+// UseCookieJar set the cookie jar for all requests of instance,
+// the cookies are shared between the requests.
+func (ins *Instance) UseCookieJar(jar http.CookieJar) *Instance {
+	ins.cookieJar = jar
+	return ins
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCookieServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("jt")
+		if err != nil {
+			http.SetCookie(w, &http.Cookie{
+				Name:  "jt",
+				Value: "abcd",
+				Path:  "/",
+			})
+			return
+		}
+		w.Write([]byte(cookie.Value))
+	}))
+}
+
+func TestWithCookieJar(t *testing.T) {
+	assert := assert.New(t)
+	ts := newCookieServer()
+	defer ts.Close()
+
+	jar := NewDefaultCookieJar()
+	_, body, err := Get(ts.URL).WithCookieJar(jar).Do()
+	assert.Nil(err)
+	assert.Empty(body)
+
+	_, body, err = Get(ts.URL).WithCookieJar(jar).Do()
+	assert.Nil(err)
+	assert.Equal("abcd", string(body))
+
+	// 未设置 cookie jar 的请求不受影响
+	_, body, err = Get(ts.URL).Do()
+	assert.Nil(err)
+	assert.Empty(body)
+	assert.Nil(http.DefaultClient.Jar)
+}
+
+func TestInstanceUseCookieJar(t *testing.T) {
+	assert := assert.New(t)
+	ts := newCookieServer()
+	defer ts.Close()
+
+	ins := NewInstanceWithConfig(Config{
+		BaseURL: ts.URL,
+	}).UseCookieJar(NewDefaultCookieJar())
+	_, body, err := ins.Get("/").Do()
+	assert.Nil(err)
+	assert.Empty(body)
+
+	_, body, err = ins.Clone().Get("/").Do()
+	assert.Nil(err)
+	assert.Equal("abcd", string(body))
+}
+
+func TestDefaultCookieJarPublicSuffix(t *testing.T) {
+	assert := assert.New(t)
+	jar := NewDefaultCookieJar()
+	u, _ := url.Parse("https://a.example.co.uk/")
+	jar.SetCookies(u, []*http.Cookie{
+		{
+			Name:   "suffix",
+			Value:  "1",
+			Domain: "co.uk",
+		},
+		{
+			Name:   "site",
+			Value:  "1",
+			Domain: "example.co.uk",
+		},
+	})
+	// public suffix 的 cookie 被拒绝
+	other, _ := url.Parse("https://b.other.co.uk/")
+	assert.Empty(jar.Cookies(other))
+	site, _ := url.Parse("https://b.example.co.uk/")
+	cookies := jar.Cookies(site)
+	assert.Equal(1, len(cookies))
+	assert.Equal("site", cookies[0].Name)
+}
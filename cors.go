@@ -0,0 +1,101 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// HeaderOrigin origin
+	HeaderOrigin = "Origin"
+	// HeaderAccessControlRequestMethod access control request method
+	HeaderAccessControlRequestMethod = "Access-Control-Request-Method"
+	// HeaderAccessControlRequestHeaders access control request headers
+	HeaderAccessControlRequestHeaders = "Access-Control-Request-Headers"
+	// HeaderAccessControlAllowOrigin access control allow origin
+	HeaderAccessControlAllowOrigin = "Access-Control-Allow-Origin"
+	// HeaderAccessControlAllowMethods access control allow methods
+	HeaderAccessControlAllowMethods = "Access-Control-Allow-Methods"
+	// HeaderAccessControlAllowHeaders access control allow headers
+	HeaderAccessControlAllowHeaders = "Access-Control-Allow-Headers"
+	// HeaderAccessControlAllowCredentials access control allow credentials
+	HeaderAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	// HeaderAccessControlExposeHeaders access control expose headers
+	HeaderAccessControlExposeHeaders = "Access-Control-Expose-Headers"
+	// HeaderAccessControlMaxAge access control max age
+	HeaderAccessControlMaxAge = "Access-Control-Max-Age"
+)
+
+type (
+	// CORSHeaders the access control headers of response
+	CORSHeaders struct {
+		AllowOrigin      string   `json:"allowOrigin,omitempty"`
+		AllowMethods     []string `json:"allowMethods,omitempty"`
+		AllowHeaders     []string `json:"allowHeaders,omitempty"`
+		AllowCredentials bool     `json:"allowCredentials,omitempty"`
+		ExposeHeaders    []string `json:"exposeHeaders,omitempty"`
+		// MaxAge max age in seconds
+		MaxAge int `json:"maxAge,omitempty"`
+	}
+)
+
+// splitHeaderValues split the comma separated header values
+func splitHeaderValues(value string) []string {
+	if value == "" {
+		return nil
+	}
+	arr := strings.Split(value, ",")
+	result := make([]string, 0, len(arr))
+	for _, v := range arr {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// SetCORSHeaders set the headers of cors preflight request,
+// the empty value will be ignored.
+func (d *Dusk) SetCORSHeaders(origin, method, headers string) *Dusk {
+	if origin != "" {
+		d.Set(HeaderOrigin, origin)
+	}
+	if method != "" {
+		d.Set(HeaderAccessControlRequestMethod, method)
+	}
+	if headers != "" {
+		d.Set(HeaderAccessControlRequestHeaders, headers)
+	}
+	return d
+}
+
+// GetCORSResponseHeaders get the access control headers of response,
+// it should be called after Do.
+func (d *Dusk) GetCORSResponseHeaders() (headers CORSHeaders) {
+	if d.Response == nil {
+		return
+	}
+	h := d.Response.Header
+	headers.AllowOrigin = h.Get(HeaderAccessControlAllowOrigin)
+	headers.AllowMethods = splitHeaderValues(h.Get(HeaderAccessControlAllowMethods))
+	headers.AllowHeaders = splitHeaderValues(h.Get(HeaderAccessControlAllowHeaders))
+	headers.AllowCredentials = h.Get(HeaderAccessControlAllowCredentials) == "true"
+	headers.ExposeHeaders = splitHeaderValues(h.Get(HeaderAccessControlExposeHeaders))
+	headers.MaxAge, _ = strconv.Atoi(h.Get(HeaderAccessControlMaxAge))
+	return
+}
@@ -0,0 +1,47 @@
+package dusk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions ||
+			r.Header.Get(HeaderOrigin) != "https://aslant.site" ||
+			r.Header.Get(HeaderAccessControlRequestMethod) != "PUT" ||
+			r.Header.Get(HeaderAccessControlRequestHeaders) != "X-Token" {
+			w.WriteHeader(400)
+			return
+		}
+		h := w.Header()
+		h.Set(HeaderAccessControlAllowOrigin, "https://aslant.site")
+		h.Set(HeaderAccessControlAllowMethods, "GET, POST,PUT")
+		h.Set(HeaderAccessControlAllowHeaders, "X-Token")
+		h.Set(HeaderAccessControlAllowCredentials, "true")
+		h.Set(HeaderAccessControlExposeHeaders, "X-Response-Id")
+		h.Set(HeaderAccessControlMaxAge, "600")
+		w.WriteHeader(204)
+	}))
+	defer ts.Close()
+
+	d := newDusk(http.MethodOptions, ts.URL).
+		SetCORSHeaders("https://aslant.site", "PUT", "X-Token")
+	resp, _, err := d.Do()
+	assert.Nil(err)
+	assert.Equal(204, resp.StatusCode)
+	assert.Equal(CORSHeaders{
+		AllowOrigin:      "https://aslant.site",
+		AllowMethods:     []string{"GET", "POST", "PUT"},
+		AllowHeaders:     []string{"X-Token"},
+		AllowCredentials: true,
+		ExposeHeaders:    []string{"X-Response-Id"},
+		MaxAge:           600,
+	}, d.GetCORSResponseHeaders())
+
+	assert.Equal(CORSHeaders{}, Get(ts.URL).GetCORSResponseHeaders())
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// quoteShell quote the string for shell with single quote
+func quoteShell(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// getCurlParams get the method, url, header and body of request,
+// it gets from the request if it's done, otherwise from the builder state.
+func (d *Dusk) getCurlParams() (method, requestURL string, header http.Header, body []byte, stdinBody bool) {
+	req := d.Request
+	if req != nil {
+		method = req.Method
+		requestURL = req.URL.String()
+		header = req.Header.Clone()
+		if req.GetBody != nil {
+			r, err := req.GetBody()
+			if err == nil {
+				body, _ = ioutil.ReadAll(r)
+				r.Close()
+			}
+		} else if req.Body != nil && req.Body != http.NoBody {
+			stdinBody = true
+		}
+		return
+	}
+	method = d.method
+	requestURL = d.GetURL()
+	tmp := &http.Request{
+		Header: make(http.Header),
+	}
+	addConfigHeader(tmp, getDefaultConfig())
+	for k, values := range d.header {
+		for _, v := range values {
+			tmp.Header.Add(k, v)
+		}
+	}
+	for _, c := range d.cookies {
+		tmp.AddCookie(c)
+	}
+	header = tmp.Header
+	data, err := d.getSendData()
+	if err != nil || data == nil {
+		return
+	}
+	contentType := jsonType
+	if _, ok := data.(io.Reader); ok {
+		// reader 的数据读取后无法再次使用，因此从标准输入读取
+		stdinBody = true
+	} else {
+		buf, t, err := d.marshalData(data)
+		if err == nil {
+			body = buf
+			contentType = t
+		}
+	}
+	// 与发送请求时一致，未设置 content type 时默认设置
+	if !d.noAutoContentType && header.Get(HeaderContentType) == "" {
+		switch contentType {
+		case formType:
+			contentType = MIMEApplicationFormUrlencoded
+		default:
+			contentType = MIMEApplicationJSON
+		}
+		header.Set(HeaderContentType, contentType)
+	}
+	return
+}
+
+// ToCurl convert the request to curl command for debugging,
+// it works before or after request is done. The values of
+// redact headers and the headers added by RedactHeader will be
+// replaced by ***, e.g. Authorization.
+// The binary body is encoded to base64 and piped to curl.
+func (d *Dusk) ToCurl(redactHeaders ...string) string {
+	method, requestURL, header, body, stdinBody := d.getCurlParams()
+	header = redactHeader(header, append(redactHeaders, d.redactedHeaders...)...)
+
+	prefix := ""
+	args := []string{
+		"curl -X " + method + " " + quoteShell(requestURL),
+	}
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range header[key] {
+			args = append(args, "-H "+quoteShell(key+": "+value))
+		}
+	}
+	switch {
+	case len(body) != 0 && utf8.Valid(body):
+		args = append(args, "--data-raw "+quoteShell(string(body)))
+	case len(body) != 0:
+		// 二进制数据转换为 base64，解码后通过标准输入传给 curl
+		prefix = "echo " + quoteShell(base64.StdEncoding.EncodeToString(body)) + " | base64 -d | "
+		args = append(args, "--data-binary @-")
+	case stdinBody:
+		args = append(args, "--data-binary @-")
+	}
+	return prefix + strings.Join(args, " \\\n  ")
+}
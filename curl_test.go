@@ -0,0 +1,81 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToCurl(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	newRequest := func() *Dusk {
+		return Post(ts.URL+"/users/:id").
+			Param("id", "1").
+			Query("type", "vip").
+			Set("Authorization", "Bearer token").
+			Send(map[string]string{
+				"name": "tree's",
+			})
+	}
+
+	t.Run("json post", func(t *testing.T) {
+		assert := assert.New(t)
+		expected := "curl -X POST '" + ts.URL + "/users/1?type=vip' \\\n" +
+			"  -H 'Authorization: ***' \\\n" +
+			"  -H 'Content-Type: application/json' \\\n" +
+			`  --data-raw '{"name":"tree'\''s"}'`
+		d := newRequest()
+		// 请求前根据设置的参数生成
+		assert.Equal(expected, d.ToCurl("authorization"))
+		_, _, err := d.Do()
+		assert.Nil(err)
+		// 请求后根据 request 生成
+		assert.Equal(expected, d.ToCurl("authorization"))
+
+		assert.Equal("curl -X POST '"+ts.URL+"/users/1?type=vip' \\\n"+
+			"  -H 'Authorization: Bearer token' \\\n"+
+			"  -H 'Content-Type: application/json' \\\n"+
+			`  --data-raw '{"name":"tree'\''s"}'`, newRequest().ToCurl())
+
+		// 通过 RedactHeader 设置
+		assert.Equal(expected, newRequest().RedactHeader("Authorization").ToCurl())
+	})
+
+	t.Run("binary body", func(t *testing.T) {
+		assert := assert.New(t)
+		d := Post(ts.URL).
+			Type("application/octet-stream").
+			Send(bytes.NewReader([]byte{0xff, 0xfe}))
+		// 请求前无法读取 reader 的数据
+		assert.Equal("curl -X POST '"+ts.URL+"' \\\n"+
+			"  -H 'Content-Type: application/octet-stream' \\\n"+
+			"  --data-binary @-", d.ToCurl())
+
+		_, _, err := d.Do()
+		assert.Nil(err)
+		assert.Equal("echo '//4=' | base64 -d | curl -X POST '"+ts.URL+"' \\\n"+
+			"  -H 'Content-Type: application/octet-stream' \\\n"+
+			"  --data-binary @-", d.ToCurl())
+	})
+}
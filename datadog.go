@@ -0,0 +1,86 @@
+//go:build datadog
+// +build datadog
+
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"net"
+	"net/http"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// TraceToDatadog set the url, method, network and timing tags of http trace to span
+func TraceToDatadog(ht *HTTPTrace, span ddtrace.Span) {
+	if ht == nil || span == nil {
+		return
+	}
+	stats := ht.Stats()
+	ht.RLock()
+	method := ht.Method
+	url := ht.URL
+	host := ht.Host
+	addr := ht.Addr
+	ht.RUnlock()
+	if url != "" {
+		span.SetTag("http.url", url)
+	}
+	if method != "" {
+		span.SetTag("http.method", method)
+	}
+	if host != "" {
+		span.SetTag("peer.hostname", host)
+	}
+	if addr != "" {
+		ip, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			ip = addr
+		}
+		span.SetTag("network.destination.ip", ip)
+	}
+	span.SetTag("http.dns_lookup_ms", toHARMilliseconds(stats.DNSLookup))
+	span.SetTag("http.tcp_ms", toHARMilliseconds(stats.TCPConnection))
+	span.SetTag("http.tls_ms", toHARMilliseconds(stats.TLSHandshake))
+	span.SetTag("http.server_ms", toHARMilliseconds(stats.ServerProcessing))
+	span.SetTag("http.transfer_ms", toHARMilliseconds(stats.ContentTransfer))
+}
+
+// TraceToDatadog set the url, method, resource(method and label)
+// and the tags of http trace to span, the trace should be enabled by EnableTrace.
+func (d *Dusk) TraceToDatadog(span ddtrace.Span) {
+	if span == nil {
+		return
+	}
+	span.SetTag("http.url", d.GetURL())
+	span.SetTag("http.method", d.GetMethod())
+	span.SetTag(ext.ResourceName, d.GetMethod()+" "+d.GetLabel())
+	TraceToDatadog(d.GetHTTPTrace(), span)
+}
+
+// InjectDatadogTrace inject the trace context of span in request's context
+// to the request header, it does nothing if there is no span.
+func InjectDatadogTrace(t ddtrace.Tracer) RequestListener {
+	return func(req *http.Request, _ *Dusk) error {
+		span, ok := tracer.SpanFromContext(req.Context())
+		if !ok {
+			return nil
+		}
+		return t.Inject(span.Context(), tracer.HTTPHeadersCarrier(req.Header))
+	}
+}
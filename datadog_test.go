@@ -0,0 +1,100 @@
+//go:build datadog
+// +build datadog
+
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func TestDatadog(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	var traceID string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID = r.Header.Get(tracer.DefaultTraceIDHeader)
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "http.request")
+	d := Get(ts.URL).
+		SetContext(ctx).
+		EnableTrace().
+		Label("Ping").
+		AddRequestListener(InjectDatadogTrace(mt.(ddtrace.Tracer)), EventTypeBefore)
+	_, _, err := d.Do()
+	assert.Nil(err)
+	d.TraceToDatadog(span)
+	span.Finish()
+
+	assert.Equal(strconv.FormatUint(span.Context().TraceID(), 10), traceID)
+	spans := mt.FinishedSpans()
+	assert.Equal(1, len(spans))
+	tags := spans[0].Tags()
+	assert.Equal(ts.URL, tags["http.url"])
+	assert.Equal("GET", tags["http.method"])
+	assert.Equal("GET Ping", tags[ext.ResourceName])
+	assert.Equal("127.0.0.1", tags["network.destination.ip"])
+	for _, key := range []string{
+		"http.dns_lookup_ms",
+		"http.tcp_ms",
+		"http.tls_ms",
+		"http.server_ms",
+		"http.transfer_ms",
+	} {
+		_, ok := tags[key]
+		assert.True(ok, key+" should be set")
+	}
+}
+
+func TestTraceToDatadog(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	d := Post(ts.URL).EnableTrace()
+	_, _, err := d.Do()
+	assert.Nil(err)
+
+	span := tracer.StartSpan("http.request")
+	TraceToDatadog(d.GetHTTPTrace(), span)
+	span.Finish()
+
+	spans := mt.FinishedSpans()
+	assert.Equal(1, len(spans))
+	tags := spans[0].Tags()
+	assert.Equal(ts.URL, tags["http.url"])
+	assert.Equal("POST", tags["http.method"])
+}
@@ -16,14 +16,22 @@ package dusk
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptrace"
 	"net/url"
+	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dsnet/compress/brotli"
@@ -35,6 +43,10 @@ const (
 	MIMEApplicationJSON = "application/json"
 	// MIMEApplicationFormUrlencoded form url encoded
 	MIMEApplicationFormUrlencoded = "application/x-www-form-urlencoded"
+	// MIMETextPlain text plain
+	MIMETextPlain = "text/plain"
+	// MIMEApplicationProblemJSON problem details json(RFC 7807)
+	MIMEApplicationProblemJSON = "application/problem+json"
 	// HeaderContentType content type
 	HeaderContentType = "Content-Type"
 	// HeaderContentEncoding content encoding
@@ -43,6 +55,8 @@ const (
 	HeaderContentLength = "Content-Length"
 	// HeaderAcceptEncoding accept encoding
 	HeaderAcceptEncoding = "Accept-Encoding"
+	// HeaderLocation location
+	HeaderLocation = "Location"
 	// GzipEncoding gzip encoding
 	GzipEncoding = "gzip"
 	// SnappyEncoding snappy encoding
@@ -55,6 +69,10 @@ const (
 
 	httpProtocol  = "http://"
 	httpsProtocol = "https://"
+
+	// maxRewindableBodySize the max size of reader which will be buffered
+	// for GetBody, the larger reader can't be retried or redirected with body
+	maxRewindableBodySize = 1024 * 1024
 )
 
 const (
@@ -66,17 +84,43 @@ const (
 	EventTypeAfter
 )
 
+var (
+	// ErrTooManyRedirects too many redirects error
+	ErrTooManyRedirects = errors.New("too many redirects")
+	// ErrCrossHostRedirect redirect to a different host error
+	ErrCrossHostRedirect = errors.New("redirect to a different host is not allowed")
+	// ErrEmptyBody the body of 2xx response is empty
+	ErrEmptyBody = errors.New("response body is empty")
+
+	// strictResponseHeaders the headers should not have conflicting values
+	strictResponseHeaders = []string{
+		HeaderContentLength,
+		HeaderContentType,
+		HeaderLocation,
+	}
+)
+
 var (
 	globalRequestEvents  []*RequestEvent
 	globalResponseEvents []*ResponseEvent
 	globalErrorListeners []ErrorListener
 	doneListeners        []DoneListener
+	// globalErrorListenerIDs doneListenerIDs 与 listener 一一对应的 id，用于删除
+	globalErrorListenerIDs []ListenerID
+	doneListenerIDs        []ListenerID
+	listenerIDCounter      ListenerID
+	// globalListenersLock lock for global listeners
+	globalListenersLock sync.RWMutex
 
 	// defaultConfig default config for all request
 	defaultConfig *Config
+	// defaultConfigLock lock for default config
+	defaultConfigLock sync.RWMutex
 )
 
 type (
+	contextKey struct{}
+
 	// Config the config for request
 	Config struct {
 		// BaseURL it will be prepended to url unless url is absolute.
@@ -85,6 +129,8 @@ type (
 		Headers http.Header
 		// Timeout timeout for request
 		Timeout time.Duration
+		// LocalAddr the local ip for request
+		LocalAddr string
 	}
 	// Decoder compression decoder
 	Decoder func(*http.Response) ([]byte, error)
@@ -107,53 +153,226 @@ type (
 		Body []byte
 		// Err request error
 		Err error
+		// Redirects the requests of redirects, only recorded if TrackRedirects
+		Redirects []*http.Request
 
 		client         *http.Client
 		m              map[string]interface{}
 		header         http.Header
+		cookies        []*http.Cookie
 		params         map[string]string
 		query          url.Values
+		form           url.Values
 		data           interface{}
 		ctx            context.Context
 		doneListeners  []DoneListener
 		requestEvents  []*RequestEvent
 		responseEvents []*ResponseEvent
 		errorListeners []ErrorListener
-		url            string
-		path           string
-		method         string
-		timeout        time.Duration
-		ht             *HTTPTrace
-		enabledTrace   bool
+		// errorListenerIDs doneListenerIDs 与 listener 一一对应的 id，用于删除，未指定的为 0
+		errorListenerIDs []ListenerID
+		doneListenerIDs  []ListenerID
+		url              string
+		path             string
+		method           string
+		timeout          time.Duration
+		ht               *HTTPTrace
+		enabledTrace     bool
+		// maxRedirects 最大的重定向次数，仅在 redirectLimited 为 true 时生效
+		maxRedirects    int
+		redirectLimited bool
+		// sameHostRedirectsOnly 只允许重定向至相同的 host
+		sameHostRedirectsOnly bool
+		redirects             []RedirectInfo
+		trackRedirects        bool
+		headerOrder           []string
+		retryAttempts         int
+		attemptTimeout        time.Duration
+		retries               int
+		// transportSetters 如果有设置，则复制 transport 后调整
+		transportSetters []TransportSetter
+		// clonedTransport 仅用于本次请求的 transport，完成后关闭空闲连接
+		clonedTransport *http.Transport
+		// transportCache instance 调整后共用的 transport
+		transportCache *transportCache
+		// clientCerts 客户端证书，在复制的 transport 中设置
+		clientCerts []tls.Certificate
+		// dialer 如果有设置，则 transport 使用此 dialer 建立连接
+		dialer         *net.Dialer
+		uploadProgress ProgressListener
+		// noAutoContentType 不自动设置 content type
+		noAutoContentType bool
+		cacheTTL          time.Duration
+		cacheKeyFromBody  bool
+		maxStale          time.Duration
+		staleErr          error
+		bodyHasher        BodyHasher
+		unchanged         bool
+		clock             Clock
+		eventLog          EventLog
+		// pipeWriter 如果有设置，响应数据直接写入，不再缓存至 Body
+		pipeWriter       io.Writer
+		bytesWritten     int64
+		downloadProgress ProgressListener
+		// buildErr 设置请求参数时出现的错误，在发送请求时返回
+		buildErr error
+		// bodyJSON 缓存 Body 解析后的数据，bodyJSONSource 为解析时的 Body
+		bodyJSON       interface{}
+		bodyJSONSource []byte
+		bodyJSONParsed bool
+		// cancels 超时 context 的 cancel 函数，在 Close 时调用
+		cancels []context.CancelFunc
+		// stream 如果为 true，响应数据不读取，由调用者通过 GetResponseReader 读取
+		stream         bool
+		responseReader io.ReadCloser
+		h2Priority     *H2Priority
+		// label 请求的逻辑名称，用于统计分组
+		label string
+		// sendContentType 如果有设置，则使用对应的 codec 序列化数据
+		sendContentType string
+		into            interface{}
+		decodeErr       error
+		// redactedHeaders 调试输出时需要隐藏的请求头
+		redactedHeaders []string
+		headerPolicies  []*HeaderPolicy
+		rateLimiter     *rateLimiter
+		// host 如果有设置，则覆盖请求的 Host（与 url 的 host 不同）
+		host string
+		// prefetch 是否预取的请求
+		prefetch bool
+		// bodyBuffered reader 的数据是否全部读取至内存，以便重试时重发
+		bodyBuffered bool
+		cookieJar    http.CookieJar
+		// requestTransform 发送请求前最后执行的调整函数
+		requestTransform func(*http.Request) error
+		// redirectPolicy 如果有设置，则优先使用此重定向策略
+		redirectPolicy RedirectPolicy
+		// cacheStore http 缓存（etag、max-age）的存储
+		cacheStore CacheStore
+		// responseTransform 读取响应数据后执行的转换函数
+		responseTransform func(*http.Response, []byte) ([]byte, error)
+		// cacheHit 响应是否从缓存中获取（未发送请求）
+		cacheHit bool
+		// prefetchDone 预取完成时调用，通知等待中的请求
+		prefetchDone func()
 	}
 	// RequestEvent request event
 	RequestEvent struct {
 		ln RequestListener
 		t  int
+		// name 监听的名称，用于删除
+		name string
+		// id 全局监听的 id，用于删除
+		id ListenerID
+		// priority 优先级，越小越先执行
+		priority int
 	}
 	// ResponseEvent response event
 	ResponseEvent struct {
-		ln ResponseListener
-		t  int
+		ln       ResponseListener
+		t        int
+		name     string
+		id       ListenerID
+		priority int
+	}
+	// ListenerID the id of listener, it's used to remove the listener
+	ListenerID uint64
+	readCloser struct {
+		io.Reader
+		io.Closer
+	}
+	// nopCloseReadSeeker the read seeker which isn't closed by transport
+	nopCloseReadSeeker struct {
+		io.ReadSeeker
+	}
+	// MalformedResponseError malformed response error
+	MalformedResponseError struct {
+		// Header the name of header
+		Header string
+		// Values the conflicting values of header
+		Values []string
+	}
+	// ResponseError the error of unexpected status code
+	ResponseError struct {
+		// StatusCode the status code of response
+		StatusCode int
+		// Body the body of response
+		Body []byte
+		// Method the method of request
+		Method string
+		// URL the url of request
+		URL string
 	}
 )
 
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status code %d", e.Method, e.URL, e.StatusCode)
+}
+
+func (e *MalformedResponseError) Error() string {
+	return fmt.Sprintf("malformed response: conflicting %s header values %q and %q", e.Header, e.Values[0], e.Values[1])
+}
+
+// nextListenerID get the next id of global listener,
+// it should be called with the global listeners lock
+func nextListenerID() ListenerID {
+	listenerIDCounter++
+	return listenerIDCounter
+}
+
+// newListenerID get the next id of the listener of instance or dusk
+func newListenerID() ListenerID {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
+	return nextListenerID()
+}
+
 // AddRequestListener add request listener for all http requset,
 // it will be called before or after http request.
 // If return new request, it will be overrded the original request.
 // If return new error, it will return error and abort request.
-func AddRequestListener(ln RequestListener, eventType int) {
+// The returned id can be used to remove the listener.
+func AddRequestListener(ln RequestListener, eventType int) ListenerID {
+	return AddRequestListenerWithPriority(ln, eventType, 0)
+}
+
+// AddRequestListenerWithPriority add request listener with priority for all
+// http request, the lower priority runs earlier, AddRequestListener is priority 0.
+func AddRequestListenerWithPriority(ln RequestListener, eventType, priority int) ListenerID {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
 	if globalRequestEvents == nil {
 		globalRequestEvents = make([]*RequestEvent, 0)
 	}
+	id := nextListenerID()
 	globalRequestEvents = append(globalRequestEvents, &RequestEvent{
-		ln: ln,
-		t:  eventType,
+		ln:       ln,
+		t:        eventType,
+		id:       id,
+		priority: priority,
 	})
+	return id
+}
+
+// RemoveRequestListener remove the global request listener of id,
+// the requests which are created won't be affected.
+func RemoveRequestListener(id ListenerID) {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
+	// 生成新的 slice，不影响已创建的请求
+	events := make([]*RequestEvent, 0, len(globalRequestEvents))
+	for _, event := range globalRequestEvents {
+		if event.id != id {
+			events = append(events, event)
+		}
+	}
+	globalRequestEvents = events
 }
 
 // ClearRequestListener clear global request listener
 func ClearRequestListener() {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
 	globalRequestEvents = nil
 }
 
@@ -161,40 +380,110 @@ func ClearRequestListener() {
 // it will be called before or after http response.
 // If return new response, it will be overried the original response.
 // If return new error, it will return error and abort response.
-func AddResponseListener(ln ResponseListener, eventType int) {
+// The returned id can be used to remove the listener.
+func AddResponseListener(ln ResponseListener, eventType int) ListenerID {
+	return AddResponseListenerWithPriority(ln, eventType, 0)
+}
+
+// AddResponseListenerWithPriority add response listener with priority for all
+// http request, the lower priority runs earlier, AddResponseListener is priority 0.
+func AddResponseListenerWithPriority(ln ResponseListener, eventType, priority int) ListenerID {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
 	if globalResponseEvents == nil {
 		globalResponseEvents = make([]*ResponseEvent, 0)
 	}
+	id := nextListenerID()
 	globalResponseEvents = append(globalResponseEvents, &ResponseEvent{
-		ln: ln,
-		t:  eventType,
+		ln:       ln,
+		t:        eventType,
+		id:       id,
+		priority: priority,
 	})
+	return id
+}
+
+// RemoveResponseListener remove the global response listener of id,
+// the requests which are created won't be affected.
+func RemoveResponseListener(id ListenerID) {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
+	events := make([]*ResponseEvent, 0, len(globalResponseEvents))
+	for _, event := range globalResponseEvents {
+		if event.id != id {
+			events = append(events, event)
+		}
+	}
+	globalResponseEvents = events
 }
 
 // ClearResponseListener clear response listener
 func ClearResponseListener() {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
 	globalResponseEvents = nil
 }
 
-// AddErrorListener add error listener for all http request
-func AddErrorListener(ln ErrorListener) {
+// AddErrorListener add error listener for all http request,
+// the returned id can be used to remove the listener.
+func AddErrorListener(ln ErrorListener) ListenerID {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
 	if globalErrorListeners == nil {
 		globalErrorListeners = make([]ErrorListener, 0)
 	}
+	id := nextListenerID()
 	globalErrorListeners = append(globalErrorListeners, ln)
+	globalErrorListenerIDs = append(globalErrorListenerIDs, id)
+	return id
+}
+
+// RemoveErrorListener remove the global error listener of id,
+// the requests which are created won't be affected.
+func RemoveErrorListener(id ListenerID) {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
+	globalErrorListeners, globalErrorListenerIDs = removeListenerOfID(globalErrorListeners, globalErrorListenerIDs, id)
 }
 
 // ClearErrorListener clear all http error listener
 func ClearErrorListener() {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
 	globalErrorListeners = nil
+	globalErrorListenerIDs = nil
 }
 
-// AddDoneListener add done listener
-func AddDoneListener(lnList ...DoneListener) {
+// AddDoneListener add done listener, the returned id
+// can be used to remove all the listeners of this call.
+func AddDoneListener(lnList ...DoneListener) ListenerID {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
 	if doneListeners == nil {
 		doneListeners = make([]DoneListener, 0)
 	}
+	id := nextListenerID()
 	doneListeners = append(doneListeners, lnList...)
+	for range lnList {
+		doneListenerIDs = append(doneListenerIDs, id)
+	}
+	return id
+}
+
+// RemoveDoneListener remove the global done listeners of id,
+// the requests which are created won't be affected.
+func RemoveDoneListener(id ListenerID) {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
+	doneListeners, doneListenerIDs = removeListenerOfID(doneListeners, doneListenerIDs, id)
+}
+
+// ClearDoneListener clear global done listener
+func ClearDoneListener() {
+	globalListenersLock.Lock()
+	defer globalListenersLock.Unlock()
+	doneListeners = nil
+	doneListenerIDs = nil
 }
 
 func getClient(d *Dusk) *http.Client {
@@ -205,6 +494,110 @@ func getClient(d *Dusk) *http.Client {
 	return c
 }
 
+// getRequestClient get the client for current request,
+// the client will be cloned for recording redirect history,
+// so the shared client won't be modified.
+func (d *Dusk) getRequestClient() (*http.Client, error) {
+	c := getClient(d)
+	client := *c
+	if d.isTransportCustomized() {
+		transport, err := d.cloneTransport(c)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = transport
+		d.clonedTransport = transport
+	} else if d.transportCache != nil {
+		transport, err := d.getBaseTransport(c)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = transport
+	}
+	if d.cookieJar != nil {
+		client.Jar = d.cookieJar
+	}
+	if len(d.headerOrder) != 0 {
+		client.Transport = newHeaderOrderTransport(&client, d.headerOrder)
+	}
+	if d.retryAttempts > 1 || d.attemptTimeout > 0 {
+		maxAttempts := d.retryAttempts
+		if maxAttempts < 1 {
+			maxAttempts = 1
+		}
+		client.Transport = newRetryTransport(client.Transport, maxAttempts, nil, d.attemptTimeout)
+	}
+	// 启用 mock 时，匹配的请求不发送至网络
+	if isMockEnabled() {
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		client.Transport = &mockTransport{
+			base: base,
+		}
+	}
+	checkRedirect := c.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) (err error) {
+		if d.sameHostRedirectsOnly && !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			return fmt.Errorf("%w: %s -> %s", ErrCrossHostRedirect, via[0].URL.Host, req.URL.Host)
+		}
+		if d.redirectPolicy != nil {
+			err = d.redirectPolicy(req, via)
+		} else if d.redirectLimited {
+			err = d.checkRedirect(req, via)
+		} else if checkRedirect != nil {
+			err = checkRedirect(req, via)
+		} else {
+			err = defaultCheckRedirect(req, via)
+		}
+		if err != nil {
+			return
+		}
+		// 重定向的请求头有可能发送至其它 host，因此需要再次检查
+		err = d.applyHeaderPolicies(req)
+		if err != nil {
+			return
+		}
+		// 记录重定向，如果启用了 trace，则同时记录至 trace
+		info := RedirectInfo{
+			From: via[len(via)-1].URL.String(),
+			To:   req.URL.String(),
+		}
+		if req.Response != nil {
+			info.StatusCode = req.Response.StatusCode
+		}
+		d.redirects = append(d.redirects, info)
+		if d.trackRedirects {
+			d.Redirects = append(d.Redirects, req)
+		}
+		if d.ht != nil {
+			d.ht.addRedirect(info)
+		}
+		return
+	}
+	return &client, nil
+}
+
+// defaultCheckRedirect the same as http client's default policy
+func defaultCheckRedirect(_ *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+func (d *Dusk) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > d.maxRedirects {
+		// 如果禁止重定向，则直接返回重定向的响应
+		if d.maxRedirects == 0 {
+			return http.ErrUseLastResponse
+		}
+		return ErrTooManyRedirects
+	}
+	return nil
+}
+
 func snappyDecoder(resp *http.Response) (buf []byte, err error) {
 	defer resp.Body.Close()
 	data, err := ioutil.ReadAll(resp.Body)
@@ -229,6 +622,15 @@ func decode(resp *http.Response, d *Dusk, encoding string, decoder Decoder) (new
 	if resp.Header.Get(HeaderContentEncoding) != encoding {
 		return
 	}
+	// pipe 模式下数据直接写入 writer，不支持读取全部数据后解压
+	if d.isPipeMode() {
+		newErr = ErrPipeDecode
+		return
+	}
+	if d.isStreamMode() {
+		newErr = ErrStreamDecode
+		return
+	}
 
 	resp.Uncompressed = true
 	resp.Header.Del(HeaderContentEncoding)
@@ -259,6 +661,51 @@ func BrDecode(resp *http.Response, d *Dusk) (newErr error) {
 	return decode(resp, d, BrEncoding, brDecoder)
 }
 
+func gzipDecoder(resp *http.Response) (buf []byte, err error) {
+	defer resp.Body.Close()
+	r, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	buf, err = ioutil.ReadAll(r)
+	return
+}
+
+// GzipDecode support gzip decode for response,
+// if the Content-Encoding:gzip, the docode function will be called.
+// The response will be decompressed by http transport if the
+// Accept-Encoding is not set manually, otherwise it should be decoded by dusk.
+func GzipDecode(resp *http.Response, d *Dusk) (newErr error) {
+	return decode(resp, d, GzipEncoding, gzipDecoder)
+}
+
+// CheckResponseHeaders check the response headers,
+// it returns MalformedResponseError if Content-Length, Content-Type
+// or Location has conflicting duplicate values.
+func CheckResponseHeaders(resp *http.Response, _ *Dusk) (newErr error) {
+	for _, key := range strictResponseHeaders {
+		values := resp.Header[key]
+		if len(values) < 2 {
+			continue
+		}
+		first := strings.TrimSpace(values[0])
+		for _, v := range values[1:] {
+			v = strings.TrimSpace(v)
+			if v != first {
+				return &MalformedResponseError{
+					Header: key,
+					Values: []string{
+						first,
+						v,
+					},
+				}
+			}
+		}
+	}
+	return
+}
+
 // SetClient set http client for dusk
 func (d *Dusk) SetClient(client *http.Client) *Dusk {
 	d.client = client
@@ -270,6 +717,49 @@ func (d *Dusk) GetClient() *http.Client {
 	return d.client
 }
 
+// DisableRedirect disable redirect for the request,
+// the 3xx response will be returned without error.
+func (d *Dusk) DisableRedirect() *Dusk {
+	return d.MaxRedirects(0)
+}
+
+// MaxRedirects set the max redirects for the request,
+// ErrTooManyRedirects will be returned if redirects more than max.
+func (d *Dusk) MaxRedirects(max int) *Dusk {
+	d.maxRedirects = max
+	d.redirectLimited = true
+	return d
+}
+
+// SameHostRedirectsOnly reject the redirect to a different host,
+// it avoids leaking cookies or custom headers to other hosts.
+func (d *Dusk) SameHostRedirectsOnly() *Dusk {
+	d.sameHostRedirectsOnly = true
+	return d
+}
+
+// TrackRedirects record the request of every redirect to d.Redirects in order
+func (d *Dusk) TrackRedirects() *Dusk {
+	d.trackRedirects = true
+	return d
+}
+
+// StrictResponseHeaders reject the response which has conflicting
+// duplicate values of Content-Length, Content-Type or Location
+func (d *Dusk) StrictResponseHeaders() *Dusk {
+	return d.AddResponseListener(CheckResponseHeaders, EventTypeBefore)
+}
+
+// GetRedirectHistory get the redirect history of request,
+// the To of the last one is the final url,
+// it returns empty slice if no redirect occurred.
+func (d *Dusk) GetRedirectHistory() []RedirectInfo {
+	if d.redirects == nil {
+		return make([]RedirectInfo, 0)
+	}
+	return d.redirects
+}
+
 // SetValue set value
 func (d *Dusk) SetValue(k string, v interface{}) *Dusk {
 	if d.m == nil {
@@ -293,6 +783,61 @@ func (d *Dusk) Set(key, value string) *Dusk {
 	return d
 }
 
+// SetOnce set http request header only if it's not set,
+// so the value set by caller won't be overridden
+func (d *Dusk) SetOnce(key, value string) *Dusk {
+	if d.GetHeader(key) != "" {
+		return d
+	}
+	return d.Set(key, value)
+}
+
+// GetHeader get the value of http request header
+func (d *Dusk) GetHeader(key string) string {
+	return d.header.Get(key)
+}
+
+// SetHost set the host of request, it overrides the Host header
+// but the connection is still made to the host of url
+func (d *Dusk) SetHost(host string) *Dusk {
+	d.host = host
+	return d
+}
+
+// SetRequestTransform set the function to modify the request before sending,
+// it's called after all before request listeners and header policies, so it's
+// the final transform of request. The error of it will be returned by Do.
+func (d *Dusk) SetRequestTransform(fn func(*http.Request) error) *Dusk {
+	d.requestTransform = fn
+	return d
+}
+
+// SetResponseTransform set the function to transform the response body,
+// it's called after the body is read and before the after response listeners,
+// the returned body replaces the body of dusk. It isn't called for pipe or
+// stream mode, and the response from http cache(it's transformed before).
+func (d *Dusk) SetResponseTransform(fn func(*http.Response, []byte) ([]byte, error)) *Dusk {
+	d.responseTransform = fn
+	return d
+}
+
+// Cookie add cookie to http request
+func (d *Dusk) Cookie(c *http.Cookie) *Dusk {
+	if d.cookies == nil {
+		d.cookies = make([]*http.Cookie, 0)
+	}
+	d.cookies = append(d.cookies, c)
+	return d
+}
+
+// AddCookie add cookie to http request by name and value
+func (d *Dusk) AddCookie(name, value string) *Dusk {
+	return d.Cookie(&http.Cookie{
+		Name:  name,
+		Value: value,
+	})
+}
+
 // Type set the content type of request
 func (d *Dusk) Type(contentType string) *Dusk {
 	switch contentType {
@@ -305,6 +850,27 @@ func (d *Dusk) Type(contentType string) *Dusk {
 	return d
 }
 
+// NoAutoContentType disable setting the content type automatically,
+// the content type of request is json(or form for url.Values) by default
+// if it is not set by Set or Type.
+func (d *Dusk) NoAutoContentType() *Dusk {
+	d.noAutoContentType = true
+	return d
+}
+
+// setAutoContentType set the content type if it is not set
+// and auto content type is not disabled
+func (d *Dusk) setAutoContentType(contentType string) {
+	if d.noAutoContentType {
+		return
+	}
+	// 手工设置的 content type 优先
+	if d.header != nil && d.header.Get(HeaderContentType) != "" {
+		return
+	}
+	d.Type(contentType)
+}
+
 // Queries set http request query
 func (d *Dusk) Queries(query map[string]string) *Dusk {
 	for k, v := range query {
@@ -322,6 +888,44 @@ func (d *Dusk) Query(key, value string) *Dusk {
 	return d
 }
 
+// QueryAdd add http request query, the value will be appended
+// to the key's values, e.g. ?id=1&id=2
+func (d *Dusk) QueryAdd(key, value string) *Dusk {
+	if d.query == nil {
+		d.query = make(url.Values)
+	}
+	d.query.Add(key, value)
+	return d
+}
+
+// QueryValues merge the values to http request query,
+// the values will be appended to the existing values.
+func (d *Dusk) QueryValues(v url.Values) *Dusk {
+	for key, values := range v {
+		for _, value := range values {
+			d.QueryAdd(key, value)
+		}
+	}
+	return d
+}
+
+// QueryStruct set http request query from struct,
+// the field name is get from query tag, json tag or field name.
+func (d *Dusk) QueryStruct(v interface{}) *Dusk {
+	values, err := EncodeQueryStruct(v)
+	if err != nil {
+		d.buildErr = err
+		return d
+	}
+	if d.query == nil {
+		d.query = make(url.Values)
+	}
+	for k, arr := range values {
+		d.query[k] = arr
+	}
+	return d
+}
+
 // Param set http request url param
 func (d *Dusk) Param(key, value string) *Dusk {
 	if d.params == nil {
@@ -337,6 +941,18 @@ func (d *Dusk) Send(data interface{}) *Dusk {
 	return d
 }
 
+// SendBytes send the raw bytes with the content type, e.g. the
+// pre-serialized protobuf or csv, the bytes are sent without marshaling.
+// The request is sent without Content-Type if it's empty.
+func (d *Dusk) SendBytes(b []byte, contentType string) *Dusk {
+	d.data = bytes.NewReader(b)
+	if contentType == "" {
+		// 不自动设置为 json
+		return d.NoAutoContentType()
+	}
+	return d.Set(HeaderContentType, contentType)
+}
+
 // SetContext set context to dusk
 func (d *Dusk) SetContext(ctx context.Context) *Dusk {
 	d.ctx = ctx
@@ -348,29 +964,51 @@ func (d *Dusk) GetContext() context.Context {
 	return d.ctx
 }
 
-// Timeout set timeout for request
+// Timeout set timeout for request, it's the total timeout
+// including all attempts of retry.
 func (d *Dusk) Timeout(timeout time.Duration) *Dusk {
 	d.timeout = timeout
 	return d
 }
 
+// OnUploadProgress set the progress listener for sending request body,
+// the total bytes is -1 if the body reader isn't seekable.
+func (d *Dusk) OnUploadProgress(fn ProgressListener) *Dusk {
+	d.uploadProgress = fn
+	return d
+}
+
 // AddDoneListener add done listener
 func (d *Dusk) AddDoneListener(lnList ...DoneListener) *Dusk {
 	if d.doneListeners == nil {
 		d.doneListeners = make([]DoneListener, 0)
 	}
 	d.doneListeners = append(d.doneListeners, lnList...)
+	for range lnList {
+		d.doneListenerIDs = append(d.doneListenerIDs, 0)
+	}
 	return d
 }
 
+// AddDoneListenerWithID add done listener, the returned id
+// can be used to remove the listener by RemoveListener.
+func (d *Dusk) AddDoneListenerWithID(ln DoneListener) ListenerID {
+	d.AddDoneListener(ln)
+	id := newListenerID()
+	d.doneListenerIDs[len(d.doneListenerIDs)-1] = id
+	return id
+}
+
 // EmitDone emit done event
 func (d *Dusk) EmitDone() error {
-	size := len(d.doneListeners)
+	// 使用当前的 listener，执行时删除 listener 不影响本次的执行
+	listeners := d.doneListeners
+	size := len(listeners)
 	if size == 0 {
 		return nil
 	}
 	for i := size - 1; i >= 0; i-- {
-		ln := d.doneListeners[i]
+		ln := listeners[i]
 		err := ln(d)
 		if err != nil {
 			return err
@@ -379,32 +1017,63 @@ func (d *Dusk) EmitDone() error {
 	return nil
 }
 
+// addRequestEvent add the request events, they are sorted by priority
+// descending and the same priority is kept in adding order,
+// so the events are executed from the end.
 func (d *Dusk) addRequestEvent(events ...*RequestEvent) *Dusk {
 	if d.requestEvents == nil {
 		d.requestEvents = make([]*RequestEvent, 0)
 	}
-	d.requestEvents = append(d.requestEvents, events...)
+	for _, e := range events {
+		index := len(d.requestEvents)
+		for index > 0 && d.requestEvents[index-1].priority < e.priority {
+			index--
+		}
+		d.requestEvents = append(d.requestEvents, nil)
+		copy(d.requestEvents[index+1:], d.requestEvents[index:])
+		d.requestEvents[index] = e
+	}
 	return d
 }
 
 // AddRequestListener add request listene
 func (d *Dusk) AddRequestListener(ln RequestListener, eventType int) *Dusk {
+	return d.AddRequestListenerWithPriority(ln, eventType, 0)
+}
+
+// AddRequestListenerWithPriority add request listener with priority,
+// the lower priority runs earlier, AddRequestListener is priority 0.
+func (d *Dusk) AddRequestListenerWithPriority(ln RequestListener, eventType, priority int) *Dusk {
 	return d.addRequestEvent(&RequestEvent{
+		ln:       ln,
+		t:        eventType,
+		priority: priority,
+	})
+}
+
+// AddRequestListenerWithID add request listener, the returned id
+// can be used to remove the listener by RemoveListener.
+func (d *Dusk) AddRequestListenerWithID(ln RequestListener, eventType int) ListenerID {
+	id := newListenerID()
+	d.addRequestEvent(&RequestEvent{
 		ln: ln,
 		t:  eventType,
+		id: id,
 	})
+	return id
 }
 
 // EmitRequest emit request event
 func (d *Dusk) EmitRequest(t int) error {
-	size := len(d.requestEvents)
+	events := d.requestEvents
+	size := len(events)
 	if size == 0 {
 		return nil
 	}
-	// 从后往前执行，后加入的先执行
+	// 从后往前执行，优先级小的先执行，相同优先级后加入的先执行
 	// 本请求的 --> instance --> global
 	for i := size - 1; i >= 0; i-- {
-		e := d.requestEvents[i]
+		e := events[i]
 		if e.t != t {
 			continue
 		}
@@ -416,30 +1085,60 @@ func (d *Dusk) EmitRequest(t int) error {
 	return nil
 }
 
+// addResponseEvent add the response events, they are sorted
+// in the same way as request events
 func (d *Dusk) addResponseEvent(events ...*ResponseEvent) *Dusk {
 	if d.responseEvents == nil {
 		d.responseEvents = make([]*ResponseEvent, 0)
 	}
-	d.responseEvents = append(d.responseEvents, events...)
+	for _, e := range events {
+		index := len(d.responseEvents)
+		for index > 0 && d.responseEvents[index-1].priority < e.priority {
+			index--
+		}
+		d.responseEvents = append(d.responseEvents, nil)
+		copy(d.responseEvents[index+1:], d.responseEvents[index:])
+		d.responseEvents[index] = e
+	}
 	return d
 }
 
 // AddResponseListener add response listener
 func (d *Dusk) AddResponseListener(ln ResponseListener, eventType int) *Dusk {
+	return d.AddResponseListenerWithPriority(ln, eventType, 0)
+}
+
+// AddResponseListenerWithPriority add response listener with priority,
+// the lower priority runs earlier, AddResponseListener is priority 0.
+func (d *Dusk) AddResponseListenerWithPriority(ln ResponseListener, eventType, priority int) *Dusk {
 	return d.addResponseEvent(&ResponseEvent{
+		ln:       ln,
+		t:        eventType,
+		priority: priority,
+	})
+}
+
+// AddResponseListenerWithID add response listener, the returned id
+// can be used to remove the listener by RemoveListener.
+func (d *Dusk) AddResponseListenerWithID(ln ResponseListener, eventType int) ListenerID {
+	id := newListenerID()
+	d.addResponseEvent(&ResponseEvent{
 		ln: ln,
 		t:  eventType,
+		id: id,
 	})
+	return id
 }
 
 // EmitResponse emit response event
 func (d *Dusk) EmitResponse(t int) error {
-	size := len(d.responseEvents)
+	events := d.responseEvents
+	size := len(events)
 	if size == 0 {
 		return nil
 	}
 	for i := size - 1; i >= 0; i-- {
-		e := d.responseEvents[i]
+		e := events[i]
 		if e.t != t {
 			continue
 		}
@@ -457,9 +1156,57 @@ func (d *Dusk) AddErrorListener(lnList ...ErrorListener) *Dusk {
 		d.errorListeners = make([]ErrorListener, 0)
 	}
 	d.errorListeners = append(d.errorListeners, lnList...)
+	for range lnList {
+		d.errorListenerIDs = append(d.errorListenerIDs, 0)
+	}
 	return d
 }
 
+// AddErrorListenerWithID add error listener, the returned id
+// can be used to remove the listener by RemoveListener.
+func (d *Dusk) AddErrorListenerWithID(ln ErrorListener) ListenerID {
+	d.AddErrorListener(ln)
+	id := newListenerID()
+	d.errorListenerIDs[len(d.errorListenerIDs)-1] = id
+	return id
+}
+
+// RemoveListener remove the request, response, error or done listener of id,
+// the new slices are created, so it's safe to be called in the listener.
+func (d *Dusk) RemoveListener(id ListenerID) *Dusk {
+	requestEvents := make([]*RequestEvent, 0, len(d.requestEvents))
+	for _, e := range d.requestEvents {
+		if e.id != id {
+			requestEvents = append(requestEvents, e)
+		}
+	}
+	d.requestEvents = requestEvents
+	responseEvents := make([]*ResponseEvent, 0, len(d.responseEvents))
+	for _, e := range d.responseEvents {
+		if e.id != id {
+			responseEvents = append(responseEvents, e)
+		}
+	}
+	d.responseEvents = responseEvents
+	d.errorListeners, d.errorListenerIDs = removeListenerOfID(d.errorListeners, d.errorListenerIDs, id)
+	d.doneListeners, d.doneListenerIDs = removeListenerOfID(d.doneListeners, d.doneListenerIDs, id)
+	return d
+}
+
+// removeListenerOfID remove the listener of id, the ids are one-to-one
+// with the listeners, the new slices are returned.
+func removeListenerOfID[T any](listeners []T, ids []ListenerID, id ListenerID) ([]T, []ListenerID) {
+	result := make([]T, 0, len(listeners))
+	resultIDs := make([]ListenerID, 0, len(ids))
+	for index, ln := range listeners {
+		if ids[index] != id {
+			result = append(result, ln)
+			resultIDs = append(resultIDs, ids[index])
+		}
+	}
+	return result, resultIDs
+}
+
 // EmitError emit error event
 func (d *Dusk) EmitError(currentErr error) error {
 	for _, ln := range d.errorListeners {
@@ -475,29 +1222,69 @@ func prependURL(requestURL string, config *Config) string {
 	// 如果有配置了base url，而且当前请求不是以绝对路径
 	if config != nil && config.BaseURL != "" {
 		if !(strings.HasPrefix(requestURL, httpProtocol) || strings.HasPrefix(requestURL, httpsProtocol)) {
-			requestURL = config.BaseURL + requestURL
+			requestURL = joinURL(config.BaseURL, requestURL)
 		}
 	}
 	return requestURL
 }
 
+// joinURL join the base url and the path of request, the path prefix
+// of base is preserved and the slashes between them are normalized,
+// the query and fragment of request are kept as they are.
+func joinURL(baseURL, requestURL string) string {
+	if requestURL == "" {
+		return baseURL
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Host == "" {
+		return baseURL + requestURL
+	}
+	requestPath := requestURL
+	rest := ""
+	if index := strings.IndexAny(requestURL, "?#"); index != -1 {
+		requestPath = requestURL[:index]
+		rest = requestURL[index:]
+	}
+	p := base.EscapedPath()
+	if requestPath != "" {
+		p = path.Join("/", p, requestPath)
+		// path.Join 会删除结尾的 /，需要保留
+		if strings.HasSuffix(requestPath, "/") && p != "/" {
+			p += "/"
+		}
+	}
+	host := base.Host
+	if base.User != nil {
+		host = base.User.String() + "@" + host
+	}
+	return base.Scheme + "://" + host + p + rest
+}
+
 func newDusk(method, requestURL string) *Dusk {
-	requestURL = prependURL(requestURL, defaultConfig)
+	d := &Dusk{}
+	d.init(method, requestURL)
+	return d
+}
+
+// init init the dusk with method, url and the global listeners
+func (d *Dusk) init(method, requestURL string) {
+	config := getDefaultConfig()
+	requestURL = prependURL(requestURL, config)
 
 	info, _ := url.Parse(requestURL)
 	path := ""
 	if info != nil {
 		path = info.Path
 	}
-	d := &Dusk{
-		url:    requestURL,
-		path:   path,
-		method: method,
-	}
-	if defaultConfig != nil && defaultConfig.Timeout != 0 {
-		d.Timeout(defaultConfig.Timeout)
+	d.url = requestURL
+	d.path = path
+	d.method = method
+	if config != nil && config.Timeout != 0 {
+		d.Timeout(config.Timeout)
 	}
 
+	globalListenersLock.RLock()
+	defer globalListenersLock.RUnlock()
 	if globalRequestEvents != nil {
 		d.addRequestEvent(globalRequestEvents...)
 	}
@@ -510,8 +1297,6 @@ func newDusk(method, requestURL string) *Dusk {
 	if doneListeners != nil {
 		d.AddDoneListener(doneListeners...)
 	}
-
-	return d
 }
 
 // Get http get request
@@ -555,40 +1340,191 @@ func addConfigHeader(req *http.Request, config *Config) {
 	}
 }
 
+// marshalData serialize the send data which isn't io.Reader by the codec
+// of SendAs, or as json(form for url.Values) by default,
+// the contentType is the content type of serialized data.
+func (d *Dusk) marshalData(data interface{}) (buf []byte, contentType string, err error) {
+	// 如果指定了 content type，则使用对应的 codec 序列化
+	if d.sendContentType != "" {
+		codec, e := GetCodec(d.sendContentType)
+		if e != nil {
+			err = e
+			return
+		}
+		buf, err = codec.Marshal(data)
+		return buf, d.sendContentType, err
+	}
+	// json stream 仅在发送时流式序列化，其它场景（如 curl）直接序列化
+	if js, ok := data.(*jsonStream); ok {
+		data = js.v
+	}
+	values, ok := data.(url.Values)
+	// 如果是form，则序列化为 x-www-form-urlencoded
+	if ok {
+		return []byte(values.Encode()), formType, nil
+	}
+	// 如果非reader 序列化为json
+	buf, err = json.Marshal(data)
+	return buf, jsonType, err
+}
+
+// newRewindableReader get the data of reader for GetBody,
+// the reader backed by buffer is used directly, the read seeker(e.g. file)
+// is used directly and seeked back to the start offset by getBody,
+// and the other reader will be buffered if it is smaller than maxRewindableBodySize.
+// The bodyBytes will be nil if the reader isn't buffered, and the getBody
+// will be nil if the reader is too large, so the request can't be retried
+// or redirected with body(307/308).
+func newRewindableReader(v io.Reader) (r io.Reader, bodyBytes []byte, getBody func() (io.ReadCloser, error), err error) {
+	switch br := v.(type) {
+	case *bytes.Buffer:
+		bodyBytes = append([]byte{}, br.Bytes()...)
+		return br, bodyBytes, newBytesGetBody(bodyBytes), nil
+	case *bytes.Reader:
+		bodyBytes = make([]byte, br.Len())
+		// 无数据时 ReadAt 返回 EOF
+		if len(bodyBytes) != 0 {
+			_, err = br.ReadAt(bodyBytes, br.Size()-int64(br.Len()))
+		}
+		return br, bodyBytes, newBytesGetBody(bodyBytes), err
+	case *strings.Reader:
+		bodyBytes = make([]byte, br.Len())
+		if len(bodyBytes) != 0 {
+			_, err = br.ReadAt(bodyBytes, br.Size()-int64(br.Len()))
+		}
+		return br, bodyBytes, newBytesGetBody(bodyBytes), err
+	case io.ReadSeeker:
+		// 无法获取当前位置的（如 pipe），则读取数据
+		start, e := br.Seek(0, io.SeekCurrent)
+		if e == nil {
+			// 发送后不关闭，以便重试或重定向时 seek 至开始位置重新发送
+			rs := nopCloseReadSeeker{
+				ReadSeeker: br,
+			}
+			getBody = func() (io.ReadCloser, error) {
+				_, err := br.Seek(start, io.SeekStart)
+				if err != nil {
+					return nil, err
+				}
+				return rs, nil
+			}
+			return rs, nil, getBody, nil
+		}
+	}
+	buf, err := ioutil.ReadAll(io.LimitReader(v, maxRewindableBodySize+1))
+	if err != nil {
+		return
+	}
+	closer, _ := v.(io.Closer)
+	// 数据过大，不缓存，已读取的数据与剩余的数据合并
+	if len(buf) > maxRewindableBodySize {
+		r = io.MultiReader(bytes.NewReader(buf), v)
+		if closer != nil {
+			r = &readCloser{
+				Reader: r,
+				Closer: closer,
+			}
+		}
+		return r, nil, nil, nil
+	}
+	if closer != nil {
+		closer.Close()
+	}
+	return bytes.NewReader(buf), buf, newBytesGetBody(buf), nil
+}
+
+// Close does nothing, the read seeker is closed after the request is done
+func (nopCloseReadSeeker) Close() error {
+	return nil
+}
+
+func newBytesGetBody(buf []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buf)), nil
+	}
+}
+
 func (d *Dusk) newRequest() (req *http.Request, err error) {
-	data := d.data
+	if d.buildErr != nil {
+		err = d.buildErr
+		return
+	}
+	err = d.bufferBody()
+	if err != nil {
+		return
+	}
+	data, err := d.getSendData()
+	if err != nil {
+		return
+	}
 	var r io.Reader
+	// bodyBytes 序列化后的数据
+	var bodyBytes []byte
+	// getBody 用于生成 GetBody 以支持重定向或重试时重发
+	var getBody func() (io.ReadCloser, error)
 	// get send data reader
 	if data != nil {
 		v, ok := data.(io.Reader)
+		js, isJSONStream := data.(*jsonStream)
 		if ok {
-			r = v
-		} else {
-			values, ok := data.(url.Values)
-			// 如果是form，则序列化为 x-www-form-urlencoded
-			if ok {
-				d.Type(formType)
-				r = bytes.NewReader([]byte(values.Encode()))
-			} else {
-				// 如果非reader 序列化为json
-				buf, e := json.Marshal(data)
-				if e != nil {
-					err = e
-					return
+			r, bodyBytes, getBody, err = newRewindableReader(v)
+			if err != nil {
+				return
+			}
+			// read seeker 在请求完成后关闭
+			if rs, ok := r.(nopCloseReadSeeker); ok {
+				if closer, ok := rs.ReadSeeker.(io.Closer); ok {
+					d.AddDoneListener(func(_ *Dusk) error {
+						closer.Close()
+						return nil
+					})
 				}
-				r = bytes.NewReader(buf)
 			}
+		} else if isJSONStream {
+			r = js.newReader()
+		} else {
+			buf, contentType, e := d.marshalData(data)
+			if e != nil {
+				err = e
+				return
+			}
+			d.setAutoContentType(contentType)
+			bodyBytes = buf
+			getBody = newBytesGetBody(buf)
+			r = bytes.NewReader(bodyBytes)
 		}
 		// 如果没有设置 content-type 默认为 json
-		if d.header == nil || d.header.Get(HeaderContentType) == "" {
-			d.Type(jsonType)
-		}
+		d.setAutoContentType(jsonType)
+	}
+	// 数据未读取（数据过大、read seeker 或 json stream）时不设置 cache key（不缓存）
+	if d.cacheKeyFromBody && (bodyBytes != nil || r == nil) {
+		d.setCacheKeyFromBody(bodyBytes)
+	}
+	// read seeker 的长度，http.NewRequest 无法获取
+	contentLength := int64(-1)
+	if _, ok := r.(nopCloseReadSeeker); ok {
+		contentLength = getReaderSize(r)
+	}
+	var pr *progressReader
+	if r != nil && d.uploadProgress != nil {
+		pr = newProgressReader(r, d.uploadProgress)
+		r = pr
 	}
 	req, err = http.NewRequest(d.method, d.GetURL(), r)
 	if err != nil {
 		return
 	}
-	addConfigHeader(req, defaultConfig)
+	// 如果读取数据时能获取长度，则设置 content length
+	if pr != nil && pr.total >= 0 {
+		req.ContentLength = pr.total
+	} else if contentLength >= 0 {
+		req.ContentLength = contentLength
+	}
+	// 使用 progress reader 或 reader 非 buffer 时 http.NewRequest 无法生成 GetBody，因此手工设置
+	if getBody != nil {
+		req.GetBody = getBody
+	}
+	addConfigHeader(req, getDefaultConfig())
 	// 如果有设置超时，则调整context
 	if d.timeout != 0 {
 		currentCtx := d.ctx
@@ -597,7 +1533,12 @@ func (d *Dusk) newRequest() (req *http.Request, err error) {
 		}
 		ctx, cancel := context.WithTimeout(currentCtx, d.timeout)
 		d.ctx = ctx
-		d.AddDoneListener(func(_ *Dusk) error {
+		d.cancels = append(d.cancels, cancel)
+		d.AddDoneListener(func(d *Dusk) error {
+			// stream 模式下成功时 body 仍需读取，在关闭 reader 时才 cancel
+			if d.isStreamMode() && d.Err == nil {
+				return nil
+			}
 			cancel()
 			return nil
 		})
@@ -613,6 +1554,13 @@ func (d *Dusk) newRequest() (req *http.Request, err error) {
 			req.Header.Add(k, v)
 		}
 	}
+	for _, c := range d.cookies {
+		req.AddCookie(c)
+	}
+	// Host 需要设置 req.Host，设置 header 会被忽略
+	if d.host != "" {
+		req.Host = d.host
+	}
 	return
 }
 
@@ -627,6 +1575,15 @@ func (d *Dusk) GetHTTPTrace() *HTTPTrace {
 	return d.ht
 }
 
+// GetTimelineStats get the timeline stats of http trace,
+// it returns nil if trace is not enabled
+func (d *Dusk) GetTimelineStats() *HTTPTimelineStats {
+	if d.ht == nil {
+		return nil
+	}
+	return d.ht.Stats()
+}
+
 func (d *Dusk) addAcceptEncoding(encoding string) {
 	accept := ""
 	header := d.header
@@ -676,11 +1633,21 @@ func (d *Dusk) isDisableCompression() bool {
 
 func (d *Dusk) do() (err error) {
 	req := d.Request
-	c := getClient(d)
+	c, err := d.getRequestClient()
+	defer func() {
+		// 在 body 关闭后执行，stream 模式下成功时由 Close 关闭
+		if !d.isStreamMode() || err != nil {
+			d.closeClonedTransport()
+		}
+	}()
+	if err != nil {
+		return
+	}
 	err = d.EmitRequest(EventTypeBefore)
+	d.logEventError(PhaseRequestBefore, err)
 	// 如果启用trace ，则添加相应的 context
 	if d.enabledTrace {
-		trace, ht := NewClientTrace()
+		trace, ht := newClientTrace(d.getClock())
 		defer ht.Finish()
 		ctx := d.ctx
 		if ctx == nil {
@@ -689,39 +1656,93 @@ func (d *Dusk) do() (err error) {
 		d.ctx = httptrace.WithClientTrace(ctx, trace)
 		req = req.WithContext(d.ctx)
 		d.Request = req
+		ht.Method = req.Method
+		ht.URL = req.URL.String()
 		d.ht = ht
 	}
 	if err != nil {
 		return
 	}
+	// 在所有 request before 事件之后执行，保证检查的是最终的请求头
+	err = d.applyHeaderPolicies(req)
+	if err != nil {
+		return
+	}
+	// 发送前最后的调整，在所有事件之后执行
+	if d.requestTransform != nil {
+		err = d.requestTransform(req)
+		if err != nil {
+			return
+		}
+	}
+	// 在所有 request before 事件之后查找缓存，保证缓存的 key 使用的是最终的请求头，
+	// 如果有可用的缓存（包括等待中的预取），则直接使用缓存的响应
+	if d.getFromCache() || d.waitPrefetch() || d.getFromHTTPCache() {
+		d.cacheHit = true
+		return
+	}
+	d.startPrefetch()
+	// 将 dusk 添加至 context 中，方便 transport 等获取
+	req = req.WithContext(ContextWithDusk(req.Context(), d))
 	resp, err := c.Do(req)
 	d.Response = resp
 	if err != nil {
+		d.logEventError(PhaseSend, err)
 		return
 	}
-	defer resp.Body.Close()
+	d.logEvent(PhaseSend, resp.Status)
+	defer func() {
+		// stream 模式下成功时由调用者关闭 body
+		if !d.isStreamMode() || err != nil {
+			resp.Body.Close()
+		}
+	}()
 	err = d.EmitRequest(EventTypeAfter)
 	if err != nil {
 		return
 	}
 	// 触发 response 事件
 	err = d.EmitResponse(EventTypeBefore)
+	d.logEventError(PhaseResponseBefore, err)
 	if err != nil {
 		return
 	}
-	// 如果已获取到数据，则返回
-	if d.Body != nil {
-		return
+	if d.isPipeMode() {
+		err = d.pipeBody(resp)
+		if err != nil {
+			d.logEventError(PhaseBodyRead, err)
+			return
+		}
+		if d.isEventLogEnabled() {
+			d.logEvent(PhaseBodyRead, fmt.Sprintf("%d bytes written", d.bytesWritten))
+		}
+	} else if d.isStreamMode() {
+		err = d.newStreamReader(resp)
+		if err != nil {
+			d.logEventError(PhaseBodyRead, err)
+			return
+		}
+	} else if d.Body == nil {
+		// 如果未获取到数据（如 br 等解压的响应事件中已读取），则读取数据
+		err = d.readBody(resp)
+		if err != nil {
+			d.logEventError(PhaseBodyRead, err)
+			return
+		}
+		if d.isEventLogEnabled() {
+			d.logEvent(PhaseBodyRead, fmt.Sprintf("%d bytes", len(d.Body)))
+		}
 	}
-
-	var buf []byte
-	buf, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return
+	// 缓存的数据已转换，不再处理
+	if d.responseTransform != nil && !d.isPipeMode() && !d.isStreamMode() && d.GetValue(FromCacheValue) == nil {
+		d.Body, err = d.responseTransform(resp, d.Body)
+		if err != nil {
+			return
+		}
 	}
-	d.Body = buf
 	// 触发 response 事件
 	err = d.EmitResponse(EventTypeAfter)
+	d.logEventError(PhaseResponseAfter, err)
 	if err != nil {
 		return
 	}
@@ -729,29 +1750,68 @@ func (d *Dusk) do() (err error) {
 	return
 }
 
+func (d *Dusk) readBody(resp *http.Response) (err error) {
+	// 如果手工设置了 Accept-Encoding，http transport 不会自动解压 gzip，
+	// 因此在读取数据时解压（包括出错的响应），保证 after 事件获取的是解压后的数据
+	err = GzipDecode(resp, d)
+	if err != nil || d.Body != nil {
+		return
+	}
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	d.Body = buf
+	return
+}
+
 // Do do http request
 func (d *Dusk) Do() (resp *http.Response, body []byte, err error) {
 	done := func() {
+		if err == nil {
+			d.decodeInto()
+		}
 		if err != nil {
+			d.logEventError(PhaseError, err)
 			newErr := d.EmitError(err)
 			if newErr != nil {
 				err = newErr
 			}
 		}
+		// 设置 Err 后再触发 done 事件，以便 done 事件中可获取请求的出错信息
+		d.Err = err
 		e := d.EmitDone()
 		if e != nil {
 			err = e
 		}
+		d.logEventError(PhaseDone, err)
 		d.Err = err
 	}
 
 	req, err := d.newRequest()
+	d.logEventError(PhaseBuild, err)
 	if err != nil {
 		done()
 		return
 	}
 	d.Request = req
+	// 在保存缓存之后通知等待预取的请求
+	defer d.finishPrefetch()
 	err = d.do()
+	if err == nil && d.cacheHit {
+		resp = d.Response
+		body = d.Body
+		done()
+		return
+	}
+	// 请求出错时，如果有可用的过期缓存，则使用过期缓存
+	if d.serveStaleIfError(err) {
+		err = nil
+		resp = d.Response
+		body = d.Body
+		done()
+		return
+	}
 	// 就算是出错了，response也有可能有返回
 	// 如自定义把400等错误转换为error
 	resp = d.Response
@@ -760,10 +1820,59 @@ func (d *Dusk) Do() (resp *http.Response, body []byte, err error) {
 		return
 	}
 	body = d.Body
+	d.saveToCache()
 	done()
 	return
 }
 
+// Close release the resources of dusk, such as the cancel functions of
+// timeout context and the buffered bodies. It is safe to be called multiple times
+// and does nothing for the request which is never executed.
+// It's recommended to defer d.Close() when the dusk is reused or pooled.
+func (d *Dusk) Close() error {
+	d.cancelContext()
+	if d.Response != nil && d.Response.Body != nil {
+		d.Response.Body.Close()
+	}
+	d.closeClonedTransport()
+	d.responseReader = nil
+	d.data = nil
+	d.form = nil
+	d.Body = nil
+	d.bodyJSON = nil
+	d.bodyJSONSource = nil
+	d.bodyJSONParsed = false
+	return nil
+}
+
+// cancelContext cancel the timeout contexts of dusk
+func (d *Dusk) cancelContext() {
+	for _, cancel := range d.cancels {
+		cancel()
+	}
+	d.cancels = nil
+}
+
+// ContextWithDusk returns a copy of ctx with the dusk
+func ContextWithDusk(ctx context.Context, d *Dusk) context.Context {
+	return context.WithValue(ctx, contextKey{}, d)
+}
+
+// FromContext get the dusk from context, it returns nil if not found
+func FromContext(ctx context.Context) *Dusk {
+	if ctx == nil {
+		return nil
+	}
+	d, _ := ctx.Value(contextKey{}).(*Dusk)
+	return d
+}
+
+// DoWithContext set the context and do http request,
+// the timeout of request will be based on the context.
+func (d *Dusk) DoWithContext(ctx context.Context) (resp *http.Response, body []byte, err error) {
+	return d.SetContext(ctx).Do()
+}
+
 // GetMethod get request method
 func (d *Dusk) GetMethod() string {
 	return d.method
@@ -791,7 +1900,233 @@ func (d *Dusk) GetPath() string {
 	return d.path
 }
 
+// Label set the logical operation name of request(e.g. GetUserProfile),
+// it's used to group the requests of templated url for metrics and logging.
+func (d *Dusk) Label(name string) *Dusk {
+	d.label = name
+	return d
+}
+
+// GetLabel get the label of request, it will be the path template
+// (before the params are replaced) if not set
+func (d *Dusk) GetLabel() string {
+	if d.label != "" {
+		return d.label
+	}
+	return d.path
+}
+
+// 判断两个 slice 是否为同一份数据（非比较内容）
+func isSameBytes(a, b []byte) bool {
+	if len(a) != len(b) || cap(a) != cap(b) {
+		return false
+	}
+	if len(a) == 0 {
+		return (a == nil) == (b == nil)
+	}
+	return &a[0] == &b[0]
+}
+
+// BodyJSON unmarshal the body as json and cache the value,
+// the cache will be invalidated if the body is reassigned.
+func (d *Dusk) BodyJSON() (v interface{}, err error) {
+	if d.bodyJSONParsed && isSameBytes(d.bodyJSONSource, d.Body) {
+		v = d.bodyJSON
+		return
+	}
+	err = json.Unmarshal(d.Body, &v)
+	if err != nil {
+		return
+	}
+	d.bodyJSON = v
+	d.bodyJSONSource = d.Body
+	d.bodyJSONParsed = true
+	return
+}
+
+// BodyJSONAs unmarshal the body as json to target,
+// if the body has been parsed by BodyJSON, the cached value will be used.
+func (d *Dusk) BodyJSONAs(target interface{}) (err error) {
+	if !d.bodyJSONParsed || !isSameBytes(d.bodyJSONSource, d.Body) {
+		return json.Unmarshal(d.Body, target)
+	}
+	buf, err := json.Marshal(d.bodyJSON)
+	if err != nil {
+		return
+	}
+	return json.Unmarshal(buf, target)
+}
+
+// CaptureResponseHeader store the value of response header to target,
+// e.g. the request id of server for log correlation.
+func (d *Dusk) CaptureResponseHeader(header string, target *string) *Dusk {
+	return d.CaptureResponseHeaders(map[string]*string{
+		header: target,
+	})
+}
+
+// CaptureResponseHeaders store the values of response headers to targets,
+// the nil target will be ignored.
+func (d *Dusk) CaptureResponseHeaders(headers map[string]*string) *Dusk {
+	return d.AddResponseListener(func(resp *http.Response, _ *Dusk) error {
+		for header, target := range headers {
+			if target != nil {
+				*target = resp.Header.Get(header)
+			}
+		}
+		return nil
+	}, EventTypeBefore)
+}
+
+// ExpectStatus return ResponseError if the status code of response
+// isn't one of the codes.
+func (d *Dusk) ExpectStatus(codes ...int) *Dusk {
+	return d.expectStatus(func(statusCode int) bool {
+		for _, code := range codes {
+			if code == statusCode {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Expect2xx return ResponseError if the status code of response isn't 2xx
+func (d *Dusk) Expect2xx() *Dusk {
+	return d.expectStatus(func(statusCode int) bool {
+		return statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices
+	})
+}
+
+// ExpectBody return ErrEmptyBody if the body of 2xx response is empty,
+// it's not checked in stream mode.
+func (d *Dusk) ExpectBody() *Dusk {
+	return d.AddResponseListener(func(resp *http.Response, d *Dusk) error {
+		if resp.StatusCode < http.StatusOK ||
+			resp.StatusCode >= http.StatusMultipleChoices ||
+			d.isStreamMode() {
+			return nil
+		}
+		size := int64(len(d.Body))
+		if d.isPipeMode() {
+			size = d.GetBytesWritten()
+		}
+		if size == 0 {
+			return ErrEmptyBody
+		}
+		return nil
+	}, EventTypeAfter)
+}
+
+func (d *Dusk) expectStatus(allowed func(int) bool) *Dusk {
+	return d.AddResponseListener(func(resp *http.Response, d *Dusk) error {
+		if allowed(resp.StatusCode) {
+			return nil
+		}
+		return &ResponseError{
+			StatusCode: resp.StatusCode,
+			Body:       d.Body,
+			Method:     d.GetMethod(),
+			URL:        d.Request.URL.String(),
+		}
+	}, EventTypeAfter)
+}
+
+// DecodeByStatus decode the response body by the decoder of status code,
+// it will be called after response, e.g. a 200 body decodes into a success type
+// and a 422 body decodes into a validation error type.
+// The error of decoder will be returned as the error of request.
+func (d *Dusk) DecodeByStatus(decoders map[int]func([]byte) error) *Dusk {
+	return d.AddResponseListener(func(resp *http.Response, d *Dusk) error {
+		fn, ok := decoders[resp.StatusCode]
+		if !ok {
+			return nil
+		}
+		return fn(d.Body)
+	}, EventTypeAfter)
+}
+
+// Cursor get the value of next cursor from the json body by path,
+// the path is separated by dot, such as "meta.next",
+// it returns empty string if the cursor is not found (the last page).
+func (d *Dusk) Cursor(path string) (cursor string, err error) {
+	v, err := d.BodyJSON()
+	if err != nil {
+		return
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch data := v.(type) {
+		case map[string]interface{}:
+			v = data[key]
+		case []interface{}:
+			index, e := strconv.Atoi(key)
+			if e != nil || index < 0 || index >= len(data) {
+				return
+			}
+			v = data[index]
+		default:
+			return
+		}
+	}
+	switch value := v.(type) {
+	case string:
+		cursor = value
+	case float64:
+		cursor = strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+	default:
+		err = fmt.Errorf("cursor of %s should be string or number", path)
+	}
+	return
+}
+
 // SetConfig set config
 func SetConfig(c Config) {
+	// 复制 header，避免调用方修改后影响已设置的配置
+	c.Headers = c.Headers.Clone()
+	defaultConfigLock.Lock()
+	defer defaultConfigLock.Unlock()
 	defaultConfig = &c
 }
+
+// GetConfig get a copy of the default config, nil will be returned if it is not set
+func GetConfig() *Config {
+	config := getDefaultConfig()
+	if config == nil {
+		return nil
+	}
+	c := *config
+	c.Headers = config.Headers.Clone()
+	return &c
+}
+
+// getDefaultConfig get the default config, it's replaced but not modified
+// by the setters, so it's safe to read after the lock is released
+func getDefaultConfig() *Config {
+	defaultConfigLock.RLock()
+	defer defaultConfigLock.RUnlock()
+	return defaultConfig
+}
+
+// SetDefaultTimeout set the timeout of default config,
+// the other settings of default config will be kept.
+func SetDefaultTimeout(timeout time.Duration) {
+	defaultConfigLock.Lock()
+	defer defaultConfigLock.Unlock()
+	c := Config{}
+	if defaultConfig != nil {
+		c = *defaultConfig
+	}
+	c.Timeout = timeout
+	defaultConfig = &c
+}
+
+// GetDefaultTimeout get the timeout of default config
+func GetDefaultTimeout() time.Duration {
+	defaultConfigLock.RLock()
+	defer defaultConfigLock.RUnlock()
+	if defaultConfig == nil {
+		return 0
+	}
+	return defaultConfig.Timeout
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusktest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vicanso/dusk"
+)
+
+type (
+	// FakeClock fake clock for testing, the time only changes by Advance or Set
+	FakeClock struct {
+		sync.Mutex
+		now    time.Time
+		timers []*fakeTimer
+	}
+	fakeTimer struct {
+		clock    *FakeClock
+		c        chan time.Time
+		deadline time.Time
+		stopped  bool
+	}
+)
+
+// NewFakeClock create a fake clock
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now: now,
+	}
+}
+
+// Now get the current time of fake clock
+func (fc *FakeClock) Now() time.Time {
+	fc.Lock()
+	defer fc.Unlock()
+	return fc.now
+}
+
+// NewTimer create a timer, it fires when the clock advances past the duration
+func (fc *FakeClock) NewTimer(d time.Duration) dusk.Timer {
+	fc.Lock()
+	defer fc.Unlock()
+	ft := &fakeTimer{
+		clock:    fc,
+		c:        make(chan time.Time, 1),
+		deadline: fc.now.Add(d),
+	}
+	if d <= 0 {
+		ft.c <- fc.now
+		ft.stopped = true
+		return ft
+	}
+	fc.timers = append(fc.timers, ft)
+	return ft
+}
+
+// Sleep block until the clock advances past the duration or the context is done
+func (fc *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := fc.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C():
+		return nil
+	}
+}
+
+// Advance move the clock forward and fire the expired timers
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.Lock()
+	defer fc.Unlock()
+	fc.now = fc.now.Add(d)
+	fc.fire()
+}
+
+// Set set the time of clock and fire the expired timers
+func (fc *FakeClock) Set(now time.Time) {
+	fc.Lock()
+	defer fc.Unlock()
+	fc.now = now
+	fc.fire()
+}
+
+// Timers get the count of pending timers (include sleeping)
+func (fc *FakeClock) Timers() int {
+	fc.Lock()
+	defer fc.Unlock()
+	return len(fc.timers)
+}
+
+func (fc *FakeClock) fire() {
+	timers := make([]*fakeTimer, 0, len(fc.timers))
+	for _, ft := range fc.timers {
+		if ft.stopped {
+			continue
+		}
+		if !fc.now.Before(ft.deadline) {
+			ft.stopped = true
+			ft.c <- fc.now
+			continue
+		}
+		timers = append(timers, ft)
+	}
+	fc.timers = timers
+}
+
+func (ft *fakeTimer) C() <-chan time.Time {
+	return ft.c
+}
+
+func (ft *fakeTimer) Stop() bool {
+	ft.clock.Lock()
+	defer ft.clock.Unlock()
+	if ft.stopped {
+		return false
+	}
+	ft.stopped = true
+	timers := make([]*fakeTimer, 0, len(ft.clock.timers))
+	for _, item := range ft.clock.timers {
+		if item != ft {
+			timers = append(timers, item)
+		}
+	}
+	ft.clock.timers = timers
+	return true
+}
@@ -0,0 +1,102 @@
+package dusktest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vicanso/dusk"
+)
+
+func TestFakeClock(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Date(2019, 6, 26, 0, 0, 0, 0, time.UTC)
+	fc := NewFakeClock(start)
+	assert.Equal(start, fc.Now())
+
+	done := make(chan error)
+	go func() {
+		done <- fc.Sleep(context.Background(), time.Second)
+	}()
+	for fc.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fc.Advance(500 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatalf("sleep should not be done")
+	default:
+	}
+	fc.Advance(500 * time.Millisecond)
+	assert.Nil(<-done)
+	assert.Equal(start.Add(time.Second), fc.Now())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(context.Canceled, fc.Sleep(ctx, time.Second))
+	assert.Equal(0, fc.Timers())
+
+	timer := fc.NewTimer(time.Minute)
+	assert.True(timer.Stop())
+	assert.False(timer.Stop())
+}
+
+func TestFakeClockExpireAfter(t *testing.T) {
+	assert := assert.New(t)
+	defer dusk.ClearRequestCache()
+	count := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Write([]byte(strconv.Itoa(count)))
+	}))
+	defer ts.Close()
+
+	fc := NewFakeClock(time.Now())
+	ins := dusk.NewInstance().
+		SetClock(fc).
+		ExpireAfter(time.Minute)
+	_, body, err := ins.Get(ts.URL).Do()
+	assert.Nil(err)
+	assert.Equal("1", string(body))
+
+	fc.Advance(59 * time.Second)
+	_, body, err = ins.Get(ts.URL).Do()
+	assert.Nil(err)
+	assert.Equal("1", string(body))
+
+	fc.Advance(2 * time.Second)
+	_, body, err = ins.Get(ts.URL).Do()
+	assert.Nil(err)
+	assert.Equal("2", string(body))
+}
+
+func TestFakeClockTrace(t *testing.T) {
+	assert := assert.New(t)
+	fc := NewFakeClock(time.Now())
+	d := dusk.Get("http://aslant.site/").
+		SetClock(fc).
+		EnableTrace()
+	d.AddRequestListener(func(_ *http.Request, d *dusk.Dusk) error {
+		fc.Advance(10 * time.Millisecond)
+		return nil
+	}, dusk.EventTypeAfter)
+	d.SetClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			fc.Advance(20 * time.Millisecond)
+			return httptest.NewRecorder().Result(), nil
+		}),
+	})
+	_, _, err := d.Do()
+	assert.Nil(err)
+	assert.Equal(30*time.Millisecond, d.GetTimelineStats().Total)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
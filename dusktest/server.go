@@ -0,0 +1,288 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusktest
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/vicanso/dusk"
+)
+
+const (
+	// PhaseDNS dns lookup phase
+	PhaseDNS Phase = "dns"
+	// PhaseTCP tcp connection phase
+	PhaseTCP Phase = "tcp"
+	// PhaseTLS tls handshake phase
+	PhaseTLS Phase = "tls"
+	// PhaseWrite request write phase
+	PhaseWrite Phase = "write"
+	// PhaseServer server processing phase
+	PhaseServer Phase = "server"
+	// PhaseTransfer content transfer phase
+	PhaseTransfer Phase = "transfer"
+)
+
+var (
+	// ErrConnectionReset the connection is reset by the server of WithResetAfter
+	ErrConnectionReset = errors.New("connection is reset by test server")
+)
+
+type (
+	// Phase the phase of http timeline
+	Phase string
+	// ServerOption the option of test server
+	ServerOption func(*serverOptions)
+
+	// Server the http test server which supports latency
+	// and connection reset injection
+	Server struct {
+		*httptest.Server
+	}
+
+	serverOptions struct {
+		tls         bool
+		headerDelay time.Duration
+		bodyRate    int
+		reset       bool
+		resetAfter  int
+	}
+	serverWriter struct {
+		http.ResponseWriter
+		opts    *serverOptions
+		written int
+		reset   bool
+	}
+)
+
+// WithTLS start the server with tls, use the Client of server
+// to make requests as the certificate is self-signed
+func WithTLS() ServerOption {
+	return func(opts *serverOptions) {
+		opts.tls = true
+	}
+}
+
+// WithHeaderDelay delay before the handler is called,
+// so the response headers are sent after the delay
+func WithHeaderDelay(d time.Duration) ServerOption {
+	return func(opts *serverOptions) {
+		opts.headerDelay = d
+	}
+}
+
+// WithBodyRate write the response body at the rate of bytes per second,
+// the body is written in chunks of 1/10 second
+func WithBodyRate(bytesPerSecond int) ServerOption {
+	return func(opts *serverOptions) {
+		opts.bodyRate = bytesPerSecond
+	}
+}
+
+// WithResetAfter reset the connection after n bytes of response body
+// are written, the following writes of handler return ErrConnectionReset
+func WithResetAfter(n int) ServerOption {
+	return func(opts *serverOptions) {
+		opts.reset = true
+		opts.resetAfter = n
+	}
+}
+
+// NewServer create and start a test server of handler with options
+func NewServer(handler http.Handler, opts ...ServerOption) *Server {
+	options := &serverOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if options.headerDelay > 0 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(options.headerDelay):
+			}
+		}
+		handler.ServeHTTP(&serverWriter{
+			ResponseWriter: w,
+			opts:           options,
+		}, r)
+	}))
+	if options.tls {
+		ts.StartTLS()
+	} else {
+		ts.Start()
+	}
+	return &Server{
+		Server: ts,
+	}
+}
+
+// LocalhostURL get the url of server with localhost as host,
+// so the dns lookup phase is triggered
+func (s *Server) LocalhostURL() string {
+	info, _ := url.Parse(s.URL)
+	_, port, _ := net.SplitHostPort(info.Host)
+	info.Host = net.JoinHostPort("localhost", port)
+	return info.String()
+}
+
+// Write write the data with the rate limit and reset the connection
+// if the written bytes reach the limit
+func (w *serverWriter) Write(p []byte) (int, error) {
+	if w.reset {
+		return 0, ErrConnectionReset
+	}
+	if w.opts.reset && w.written+len(p) >= w.opts.resetAfter {
+		n, err := w.write(p[:w.opts.resetAfter-w.written])
+		if err != nil {
+			return n, err
+		}
+		w.resetConnection()
+		return n, ErrConnectionReset
+	}
+	return w.write(p)
+}
+
+func (w *serverWriter) write(p []byte) (int, error) {
+	if w.opts.bodyRate <= 0 {
+		n, err := w.ResponseWriter.Write(p)
+		w.written += n
+		return n, err
+	}
+	chunkSize := w.opts.bodyRate / 10
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+	total := 0
+	for len(p) != 0 {
+		size := chunkSize
+		if size > len(p) {
+			size = len(p)
+		}
+		n, err := w.ResponseWriter.Write(p[:size])
+		total += n
+		w.written += n
+		if err != nil {
+			return total, err
+		}
+		w.Flush()
+		p = p[size:]
+		time.Sleep(time.Duration(size) * time.Second / time.Duration(w.opts.bodyRate))
+	}
+	return total, nil
+}
+
+// Flush flush the buffered data to client
+func (w *serverWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// resetConnection close the connection with linger 0, so the client gets reset
+func (w *serverWriter) resetConnection() {
+	w.reset = true
+	w.Flush()
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		// 无法 hijack（如 http2）时中止处理
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	netConn := conn
+	// tls 连接获取底层连接
+	if c, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		netConn = c.NetConn()
+	}
+	if tcpConn, ok := netConn.(*net.TCPConn); ok {
+		_ = tcpConn.SetLinger(0)
+	}
+	netConn.Close()
+}
+
+func getPhaseDuration(stats *dusk.HTTPTimelineStats, phase Phase) (time.Duration, bool) {
+	switch phase {
+	case PhaseDNS:
+		return stats.DNSLookup, true
+	case PhaseTCP:
+		return stats.TCPConnection, true
+	case PhaseTLS:
+		return stats.TLSHandshake, true
+	case PhaseWrite:
+		return stats.RequestWrite, true
+	case PhaseServer:
+		return stats.ServerProcessing, true
+	case PhaseTransfer:
+		return stats.ContentTransfer, true
+	}
+	return 0, false
+}
+
+// AssertPhases assert the phases of timeline stats are non-zero,
+// it returns false if any phase is zero
+func AssertPhases(t testing.TB, stats *dusk.HTTPTimelineStats, phases ...Phase) bool {
+	t.Helper()
+	if stats == nil {
+		t.Errorf("timeline stats is nil, trace should be enabled")
+		return false
+	}
+	ok := true
+	for _, phase := range phases {
+		d, valid := getPhaseDuration(stats, phase)
+		if !valid {
+			t.Errorf("phase %q is invalid", phase)
+			ok = false
+			continue
+		}
+		if d <= 0 {
+			t.Errorf("phase %q of timeline should not be zero, stats: %s", phase, stats.String())
+			ok = false
+		}
+	}
+	return ok
+}
+
+// AssertNoPhases assert the phases of timeline stats are zero,
+// e.g. no dns, tcp and tls phases for the reused connection
+func AssertNoPhases(t testing.TB, stats *dusk.HTTPTimelineStats, phases ...Phase) bool {
+	t.Helper()
+	if stats == nil {
+		t.Errorf("timeline stats is nil, trace should be enabled")
+		return false
+	}
+	ok := true
+	for _, phase := range phases {
+		d, valid := getPhaseDuration(stats, phase)
+		if !valid {
+			t.Errorf("phase %q is invalid", phase)
+			ok = false
+			continue
+		}
+		if d != 0 {
+			t.Errorf("phase %q of timeline should be zero, stats: %s", phase, stats.String())
+			ok = false
+		}
+	}
+	return ok
+}
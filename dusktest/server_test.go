@@ -0,0 +1,135 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusktest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vicanso/dusk"
+)
+
+func TestServerHeaderDelay(t *testing.T) {
+	assert := assert.New(t)
+	s := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}), WithHeaderDelay(50*time.Millisecond))
+	defer s.Close()
+
+	d := dusk.Get(s.LocalhostURL()).EnableTrace()
+	_, body, err := d.Do()
+	assert.Nil(err)
+	assert.Equal("ok", string(body))
+	stats := d.GetTimelineStats()
+	AssertPhases(t, stats, PhaseDNS, PhaseTCP, PhaseServer)
+	AssertNoPhases(t, stats, PhaseTLS)
+	assert.True(stats.ServerProcessing >= 50*time.Millisecond)
+
+	// 超时小于延时则出错
+	_, _, err = dusk.Get(s.URL).
+		Timeouts(0, 0, 10*time.Millisecond, 0).
+		Do()
+	assert.NotNil(err)
+}
+
+func TestServerTLS(t *testing.T) {
+	assert := assert.New(t)
+	s := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}), WithTLS())
+	defer s.Close()
+
+	d := dusk.Get(s.URL).
+		SetClient(s.Client()).
+		EnableTrace()
+	_, body, err := d.Do()
+	assert.Nil(err)
+	assert.Equal("ok", string(body))
+	AssertPhases(t, d.GetTimelineStats(), PhaseTCP, PhaseTLS, PhaseServer)
+
+	// 连接复用
+	d = dusk.Get(s.URL).
+		SetClient(s.Client()).
+		EnableTrace()
+	_, _, err = d.Do()
+	assert.Nil(err)
+	AssertNoPhases(t, d.GetTimelineStats(), PhaseDNS, PhaseTCP, PhaseTLS)
+}
+
+func TestServerBodyRate(t *testing.T) {
+	assert := assert.New(t)
+	data := strings.Repeat("a", 100)
+	s := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(data))
+	}), WithBodyRate(500))
+	defer s.Close()
+
+	d := dusk.Get(s.URL).EnableTrace()
+	_, body, err := d.Do()
+	assert.Nil(err)
+	assert.Equal(data, string(body))
+	stats := d.GetTimelineStats()
+	AssertPhases(t, stats, PhaseTransfer)
+	// 每 50 字节一次，共 200ms
+	assert.True(stats.ContentTransfer >= 150*time.Millisecond)
+}
+
+func TestServerResetAfter(t *testing.T) {
+	assert := assert.New(t)
+	done := make(chan error, 1)
+	s := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		_, err := w.Write([]byte(strings.Repeat("a", 100)))
+		done <- err
+	}), WithResetAfter(10))
+	defer s.Close()
+
+	_, _, err := dusk.Get(s.URL).Do()
+	assert.NotNil(err)
+	assert.Equal(ErrConnectionReset, <-done)
+}
+
+type mockTB struct {
+	testing.TB
+	errors []string
+}
+
+func (m *mockTB) Helper() {}
+
+func (m *mockTB) Errorf(format string, args ...interface{}) {
+	m.errors = append(m.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertPhases(t *testing.T) {
+	assert := assert.New(t)
+	stats := &dusk.HTTPTimelineStats{
+		TCPConnection: time.Millisecond,
+	}
+	mockT := &mockTB{}
+	assert.True(AssertPhases(mockT, stats, PhaseTCP))
+	assert.True(AssertNoPhases(mockT, stats, PhaseDNS, PhaseTLS))
+	assert.Empty(mockT.errors)
+
+	assert.False(AssertPhases(mockT, stats, PhaseDNS))
+	assert.False(AssertNoPhases(mockT, stats, PhaseTCP))
+	assert.False(AssertPhases(mockT, stats, Phase("unknown")))
+	assert.False(AssertPhases(mockT, nil))
+	assert.Equal(4, len(mockT.errors))
+	assert.Contains(mockT.errors[0], `phase "dns" of timeline should not be zero`)
+}
@@ -0,0 +1,210 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrQueryStructInvalid the value for query struct is not a struct
+	ErrQueryStructInvalid = errors.New("query struct should be a struct or a pointer to struct")
+	// ErrQueryValueUnsupported the type of field can't be encoded as query value,
+	// e.g. map, chan or func
+	ErrQueryValueUnsupported = errors.New("query value type is not supported")
+
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// 获取字段对应的 query 名称，优先使用 query tag，其次为 json tag
+func getQueryFieldName(field reflect.StructField) (name string, omitEmpty bool) {
+	tag, ok := field.Tag.Lookup("query")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "-", false
+	}
+	arr := strings.Split(tag, ",")
+	name = arr[0]
+	for _, opt := range arr[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return
+}
+
+// 将基础类型转换为字符串，不支持的类型返回出错
+func formatQueryValue(name string, v reflect.Value) (string, error) {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("%w: %s is %s", ErrQueryValueUnsupported, name, v.Type())
+}
+
+func isZeroQueryValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func isNestedStruct(v reflect.Value) bool {
+	return v.Kind() == reflect.Struct && v.Type() != timeType
+}
+
+// isEmbeddedStruct check whether the field is embedded struct
+// or pointer to struct, which should be flattened
+func isEmbeddedStruct(field reflect.StructField) bool {
+	if !field.Anonymous {
+		return false
+	}
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// encodeQueryStruct encode the struct to values,
+// the field of nested struct will be named as parent.child
+func encodeQueryStruct(values url.Values, v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		// 嵌入的 struct（包括指针）展开处理
+		if isEmbeddedStruct(field) {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			err := encodeQueryStruct(values, fv, prefix)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		// 忽略非导出字段
+		if field.PkgPath != "" {
+			continue
+		}
+		name, omitEmpty := getQueryFieldName(field)
+		if name == "-" {
+			continue
+		}
+		name = prefix + name
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if omitEmpty && isZeroQueryValue(fv) {
+			continue
+		}
+		if isNestedStruct(fv) {
+			err := encodeQueryStruct(values, fv, name+".")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array {
+			for j := 0; j < fv.Len(); j++ {
+				item := fv.Index(j)
+				if item.Kind() == reflect.Ptr {
+					if item.IsNil() {
+						continue
+					}
+					item = item.Elem()
+				}
+				// struct 的数组以 name.index.child 的形式
+				if isNestedStruct(item) {
+					err := encodeQueryStruct(values, item, name+"."+strconv.Itoa(j)+".")
+					if err != nil {
+						return err
+					}
+					continue
+				}
+				str, err := formatQueryValue(name, item)
+				if err != nil {
+					return err
+				}
+				values.Add(name, str)
+			}
+			continue
+		}
+		str, err := formatQueryValue(name, fv)
+		if err != nil {
+			return err
+		}
+		values.Add(name, str)
+	}
+	return nil
+}
+
+// EncodeQueryStruct encode struct to url values,
+// the name of field is get from query tag, json tag or field name,
+// the field of nested struct will be named as parent.child.
+// The embedded struct(or pointer to struct) is flattened, and
+// ErrQueryValueUnsupported is returned for the field of unsupported type.
+func EncodeQueryStruct(data interface{}) (values url.Values, err error) {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			err = ErrQueryStructInvalid
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		err = ErrQueryStructInvalid
+		return
+	}
+	values = make(url.Values)
+	err = encodeQueryStruct(values, v, "")
+	if err != nil {
+		values = nil
+	}
+	return
+}
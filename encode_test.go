@@ -0,0 +1,175 @@
+package dusk
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeQueryStruct(t *testing.T) {
+	assert := assert.New(t)
+	type Base struct {
+		Category string `query:"category"`
+	}
+	type Params struct {
+		Base
+		Name      string    `json:"name"`
+		Account   string    `query:"account" json:"user"`
+		Age       int       `json:"age,omitempty"`
+		Count     uint8     `json:"count"`
+		Price     float64   `json:"price"`
+		VIP       bool      `json:"vip"`
+		IDs       []int     `json:"id"`
+		Empty     []string  `json:"empty,omitempty"`
+		Nick      *string   `json:"nick"`
+		Ref       *int      `json:"ref"`
+		CreatedAt time.Time `json:"createdAt"`
+		Secret    string    `json:"-"`
+		Ignore    string    `query:"-" json:"ignore"`
+		Remark    string
+		private   string
+	}
+	ref := 1
+	p := Params{
+		Base: Base{
+			Category: "vip",
+		},
+		Name:      "tree.xie",
+		Account:   "vicanso",
+		Count:     2,
+		Price:     1.5,
+		IDs:       []int{1, 2},
+		Ref:       &ref,
+		CreatedAt: time.Date(2019, 6, 26, 10, 0, 0, 0, time.UTC),
+		Secret:    "secret",
+		Ignore:    "ignore",
+		private:   "private",
+	}
+	values, err := EncodeQueryStruct(&p)
+	assert.Nil(err)
+	assert.Equal("Remark=&account=vicanso&category=vip&count=2&createdAt=2019-06-26T10%3A00%3A00Z&id=1&id=2&name=tree.xie&price=1.5&ref=1&vip=false", values.Encode())
+
+	_, err = EncodeQueryStruct("abcd")
+	assert.Equal(ErrQueryStructInvalid, err)
+	var nilParams *Params
+	_, err = EncodeQueryStruct(nilParams)
+	assert.Equal(ErrQueryStructInvalid, err)
+}
+
+func TestQueryStruct(t *testing.T) {
+	assert := assert.New(t)
+	d := Get("http://aslant.site/").
+		Query("type", "1").
+		QueryStruct(struct {
+			Name string   `query:"name"`
+			IDs  []string `query:"id"`
+		}{
+			Name: "tree.xie",
+			IDs:  []string{"1", "2"},
+		})
+	assert.Equal("http://aslant.site/?id=1&id=2&name=tree.xie&type=1", d.GetURL())
+
+	_, err := d.newRequest()
+	assert.Nil(err)
+
+	d = Get("http://aslant.site/").QueryStruct(url.Values{})
+	_, _, err = d.Do()
+	assert.Equal(ErrQueryStructInvalid, err)
+}
+
+func TestEncodeQueryStructNested(t *testing.T) {
+	assert := assert.New(t)
+	type Address struct {
+		City string `query:"city"`
+		Zip  string `query:"zip,omitempty"`
+	}
+	type Item struct {
+		ID int `query:"id"`
+	}
+	type User struct {
+		Name    string   `query:"name"`
+		Home    Address  `query:"home"`
+		Work    *Address `query:"work"`
+		Other   *Address `query:"other"`
+		Empty   Address  `query:"empty,omitempty"`
+		Items   []Item   `query:"items"`
+		Tags    []string `query:"tag"`
+		Enabled *bool    `query:"enabled"`
+	}
+	enabled := true
+	values, err := EncodeQueryStruct(User{
+		Name: "tree.xie",
+		Home: Address{
+			City: "GZ",
+			Zip:  "510000",
+		},
+		Work: &Address{
+			City: "SZ",
+		},
+		Items: []Item{
+			{ID: 1},
+			{ID: 2},
+		},
+		Tags:    []string{"a", "b"},
+		Enabled: &enabled,
+	})
+	assert.Nil(err)
+	assert.Equal("enabled=true&home.city=GZ&home.zip=510000&items.0.id=1&items.1.id=2&name=tree.xie&tag=a&tag=b&work.city=SZ", values.Encode())
+}
+
+func TestEncodeQueryStructEmbeddedPointer(t *testing.T) {
+	assert := assert.New(t)
+	type Page struct {
+		Limit int `query:"limit"`
+	}
+	type Params struct {
+		*Page
+		Name string `query:"name"`
+	}
+	values, err := EncodeQueryStruct(Params{
+		Page: &Page{
+			Limit: 10,
+		},
+		Name: "tree.xie",
+	})
+	assert.Nil(err)
+	assert.Equal("limit=10&name=tree.xie", values.Encode())
+
+	// nil 的嵌入指针忽略
+	values, err = EncodeQueryStruct(Params{
+		Name: "tree.xie",
+	})
+	assert.Nil(err)
+	assert.Equal("name=tree.xie", values.Encode())
+}
+
+func TestEncodeQueryStructUnsupported(t *testing.T) {
+	assert := assert.New(t)
+	type Params struct {
+		Name  string            `query:"name"`
+		Extra map[string]string `query:"extra"`
+	}
+	values, err := EncodeQueryStruct(Params{
+		Name: "tree.xie",
+		Extra: map[string]string{
+			"a": "1",
+		},
+	})
+	assert.Nil(values)
+	assert.True(errors.Is(err, ErrQueryValueUnsupported))
+	assert.Equal("query value type is not supported: extra is map[string]string", err.Error())
+
+	_, _, err = Get("http://aslant.site/").
+		QueryStruct(struct {
+			Fn []func() `query:"fn"`
+		}{
+			Fn: []func(){
+				func() {},
+			},
+		}).
+		Do()
+	assert.True(errors.Is(err, ErrQueryValueUnsupported))
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// EnvBaseURL the env of base url
+	EnvBaseURL = "DUSK_BASE_URL"
+	// EnvTimeout the env of timeout, e.g. 3s
+	EnvTimeout = "DUSK_TIMEOUT"
+	// EnvHeaders the env of headers, e.g. X-Token:abc;X-Type:1
+	EnvHeaders = "DUSK_HEADERS"
+)
+
+// ConfigFromEnv get the config from env(DUSK_BASE_URL, DUSK_TIMEOUT and DUSK_HEADERS),
+// the empty env will be skipped
+func ConfigFromEnv() (c Config, err error) {
+	c.BaseURL = os.Getenv(EnvBaseURL)
+	timeout := os.Getenv(EnvTimeout)
+	if timeout != "" {
+		c.Timeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			err = fmt.Errorf("parse %s fail, %w", EnvTimeout, err)
+			return
+		}
+	}
+	headers := os.Getenv(EnvHeaders)
+	if headers != "" {
+		c.Headers = make(http.Header)
+		for _, item := range strings.Split(headers, ";") {
+			if strings.TrimSpace(item) == "" {
+				continue
+			}
+			arr := strings.SplitN(item, ":", 2)
+			key := strings.TrimSpace(arr[0])
+			if len(arr) != 2 || key == "" {
+				err = fmt.Errorf("parse %s fail, invalid header %q", EnvHeaders, item)
+				return
+			}
+			c.Headers.Add(key, strings.TrimSpace(arr[1]))
+		}
+	}
+	return
+}
+
+// SetConfigFromEnv set the default config from env, see ConfigFromEnv
+func SetConfigFromEnv() error {
+	c, err := ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	SetConfig(c)
+	return nil
+}
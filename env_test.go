@@ -0,0 +1,80 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Run("empty env", func(t *testing.T) {
+		assert := assert.New(t)
+		t.Setenv(EnvBaseURL, "")
+		t.Setenv(EnvTimeout, "")
+		t.Setenv(EnvHeaders, "")
+		c, err := ConfigFromEnv()
+		assert.Nil(err)
+		assert.Equal(Config{}, c)
+	})
+
+	t.Run("parse env", func(t *testing.T) {
+		assert := assert.New(t)
+		t.Setenv(EnvBaseURL, "https://aslant.site")
+		t.Setenv(EnvTimeout, "3s")
+		t.Setenv(EnvHeaders, "X-Token: abc;X-Type:1;")
+		c, err := ConfigFromEnv()
+		assert.Nil(err)
+		assert.Equal(Config{
+			BaseURL: "https://aslant.site",
+			Timeout: 3 * time.Second,
+			Headers: http.Header{
+				"X-Token": []string{"abc"},
+				"X-Type":  []string{"1"},
+			},
+		}, c)
+	})
+
+	t.Run("invalid timeout", func(t *testing.T) {
+		t.Setenv(EnvTimeout, "3")
+		_, err := ConfigFromEnv()
+		assert.NotNil(t, err)
+	})
+
+	t.Run("invalid headers", func(t *testing.T) {
+		t.Setenv(EnvTimeout, "")
+		t.Setenv(EnvHeaders, "X-Token")
+		_, err := ConfigFromEnv()
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSetConfigFromEnv(t *testing.T) {
+	assert := assert.New(t)
+	defer SetConfig(Config{})
+	t.Setenv(EnvBaseURL, "https://aslant.site")
+	t.Setenv(EnvTimeout, "")
+	t.Setenv(EnvHeaders, "")
+	assert.Nil(SetConfigFromEnv())
+	assert.Equal("https://aslant.site/users", Get("/users").GetURL())
+
+	t.Setenv(EnvTimeout, "abc")
+	assert.NotNil(SetConfigFromEnv())
+	// 出错时不修改默认配置
+	assert.Equal("https://aslant.site", GetConfig().BaseURL)
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// PhaseBuild build request phase
+	PhaseBuild LifecyclePhase = "build"
+	// PhaseRequestBefore request before event phase
+	PhaseRequestBefore LifecyclePhase = "request-before"
+	// PhaseSend send request phase
+	PhaseSend LifecyclePhase = "send"
+	// PhaseResponseBefore response before event phase
+	PhaseResponseBefore LifecyclePhase = "response-before"
+	// PhaseBodyRead read response body phase
+	PhaseBodyRead LifecyclePhase = "body-read"
+	// PhaseResponseAfter response after event phase
+	PhaseResponseAfter LifecyclePhase = "response-after"
+	// PhaseError error event phase
+	PhaseError LifecyclePhase = "error"
+	// PhaseDone done event phase
+	PhaseDone LifecyclePhase = "done"
+
+	// 一次请求大概的事件数
+	defaultEventLogSize = 8
+)
+
+type (
+	// LifecyclePhase the phase of request lifecycle
+	LifecyclePhase string
+	// LifecycleEvent the event of request lifecycle
+	LifecycleEvent struct {
+		Time   time.Time      `json:"time,omitempty"`
+		Phase  LifecyclePhase `json:"phase,omitempty"`
+		Detail string         `json:"detail,omitempty"`
+	}
+	// EventLog the ordered lifecycle events of request
+	EventLog []LifecycleEvent
+)
+
+// String get the readable timeline of events,
+// the offset of each event is relative to the first one.
+func (el EventLog) String() string {
+	if len(el) == 0 {
+		return ""
+	}
+	start := el[0].Time
+	lines := make([]string, len(el))
+	for index, e := range el {
+		line := fmt.Sprintf("%10s %-16s", "+"+formatDuration(e.Time.Sub(start)), e.Phase)
+		if e.Detail != "" {
+			line += " " + e.Detail
+		}
+		lines[index] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// EnableEventLog enable the lifecycle event log of request
+func (d *Dusk) EnableEventLog() *Dusk {
+	if d.eventLog == nil {
+		d.eventLog = make(EventLog, 0, defaultEventLogSize)
+	}
+	return d
+}
+
+// EventLog get the lifecycle event log of request,
+// it returns nil if event log is not enabled.
+func (d *Dusk) EventLog() EventLog {
+	return d.eventLog
+}
+
+// isEventLogEnabled check whether the event log is enabled,
+// the detail which needs formatting should be built after checking
+func (d *Dusk) isEventLogEnabled() bool {
+	return d.eventLog != nil
+}
+
+// logEvent append the event to log if event log is enabled
+func (d *Dusk) logEvent(phase LifecyclePhase, detail string) {
+	if !d.isEventLogEnabled() {
+		return
+	}
+	d.eventLog = append(d.eventLog, LifecycleEvent{
+		Time:   d.getClock().Now(),
+		Phase:  phase,
+		Detail: detail,
+	})
+}
+
+// logEventError append the event to log with error as detail
+func (d *Dusk) logEventError(phase LifecyclePhase, err error) {
+	if !d.isEventLogEnabled() {
+		return
+	}
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+	}
+	d.logEvent(phase, detail)
+}
@@ -0,0 +1,96 @@
+package dusk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventLogString(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Now()
+	el := EventLog{
+		{
+			Time:  start,
+			Phase: PhaseBuild,
+		},
+		{
+			Time:   start.Add(1500 * time.Microsecond),
+			Phase:  PhaseSend,
+			Detail: "200 OK",
+		},
+	}
+	assert.Equal("   +0.00ms build\n   +1.50ms send             200 OK", el.String())
+	assert.Equal("", EventLog(nil).String())
+}
+
+func TestEventLog(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	getPhases := func(el EventLog) []LifecyclePhase {
+		phases := make([]LifecyclePhase, len(el))
+		for index, e := range el {
+			phases[index] = e.Phase
+		}
+		return phases
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		assert := assert.New(t)
+		d := Get(ts.URL)
+		_, _, err := d.Do()
+		assert.Nil(err)
+		assert.Nil(d.EventLog())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		assert := assert.New(t)
+		d := Get(ts.URL).EnableEventLog()
+		_, _, err := d.Do()
+		assert.Nil(err)
+		el := d.EventLog()
+		assert.Equal([]LifecyclePhase{
+			PhaseBuild,
+			PhaseRequestBefore,
+			PhaseSend,
+			PhaseResponseBefore,
+			PhaseBodyRead,
+			PhaseResponseAfter,
+			PhaseDone,
+		}, getPhases(el))
+		assert.Equal("200 OK", el[2].Detail)
+		assert.Equal("4 bytes", el[4].Detail)
+		assert.Equal(7, len(strings.Split(el.String(), "\n")))
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		assert := assert.New(t)
+		d := Get(ts.URL).EnableEventLog()
+		d.AddResponseListener(func(_ *http.Response, _ *Dusk) error {
+			return errors.New("abcd")
+		}, EventTypeAfter)
+		_, _, err := d.Do()
+		assert.NotNil(err)
+		el := d.EventLog()
+		assert.Equal([]LifecyclePhase{
+			PhaseBuild,
+			PhaseRequestBefore,
+			PhaseSend,
+			PhaseResponseBefore,
+			PhaseBodyRead,
+			PhaseResponseAfter,
+			PhaseError,
+			PhaseDone,
+		}, getPhases(el))
+		assert.Equal("abcd", el[5].Detail)
+		assert.Equal("abcd", el[6].Detail)
+	})
+}
@@ -0,0 +1,215 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	fileCacheTempPrefix = "tmp-"
+)
+
+type (
+	fileCacheStore struct {
+		sync.Mutex
+		dir      string
+		maxBytes int64
+		// size 估算的缓存大小，超过限制时重新扫描目录后清除
+		size int64
+	}
+	fileCacheEntry struct {
+		Key   string      `json:"key"`
+		Entry *CacheEntry `json:"entry"`
+	}
+	fileCacheInfo struct {
+		path string
+		size int64
+		// modTime 最近访问的时间（纳秒）
+		modTime int64
+	}
+)
+
+// NewFileCacheStore create a store of http cache backed by the filesystem,
+// so the cache can be shared between restarts(or processes on best-effort basis).
+// The entries are written atomically(temp file and rename), the least recently
+// used entries are removed if the total size is more than max bytes(0 means no limit),
+// and the unreadable entries are treated as misses and removed.
+func NewFileCacheStore(dir string, maxBytes int64) (CacheStore, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	s := &fileCacheStore{
+		dir:      dir,
+		maxBytes: maxBytes,
+	}
+	files, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range files {
+		s.size += info.size
+	}
+	return s, nil
+}
+
+// getPath get the file path of key, the files are sharded by
+// the first two characters of hash
+func (s *fileCacheStore) getPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// touch update the modification time of file as access time
+func touch(file string) {
+	now := GetClock().Now()
+	_ = os.Chtimes(file, now, now)
+}
+
+func (s *fileCacheStore) Get(key string) (entry *CacheEntry, ok bool) {
+	file := s.getPath(key)
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return
+	}
+	item := fileCacheEntry{}
+	err = json.Unmarshal(buf, &item)
+	// 数据损坏则删除
+	if err != nil || item.Key != key || item.Entry == nil {
+		s.remove(file)
+		return
+	}
+	touch(file)
+	return item.Entry, true
+}
+
+func (s *fileCacheStore) Set(key string, entry *CacheEntry) {
+	buf, err := json.Marshal(&fileCacheEntry{
+		Key:   key,
+		Entry: entry,
+	})
+	if err != nil {
+		return
+	}
+	file := s.getPath(key)
+	shard := filepath.Dir(file)
+	err = os.MkdirAll(shard, 0700)
+	if err != nil {
+		return
+	}
+	// 写入临时文件后 rename，保证其它读取不会读到部分数据
+	tmp, err := ioutil.TempFile(shard, fileCacheTempPrefix)
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf)
+	closeErr := tmp.Close()
+	if err != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	var prevSize int64
+	if info, e := os.Stat(file); e == nil {
+		prevSize = info.Size()
+	}
+	err = os.Rename(tmp.Name(), file)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	touch(file)
+	s.size += int64(len(buf)) - prevSize
+	if s.maxBytes > 0 && s.size > s.maxBytes {
+		s.evict(file)
+	}
+}
+
+// remove remove the file of cache
+func (s *fileCacheStore) remove(file string) {
+	s.Lock()
+	defer s.Unlock()
+	info, err := os.Stat(file)
+	if err != nil {
+		return
+	}
+	if os.Remove(file) == nil {
+		s.size -= info.Size()
+	}
+}
+
+// scan get the info of all cache files
+func (s *fileCacheStore) scan() ([]*fileCacheInfo, error) {
+	files := make([]*fileCacheInfo, 0)
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		// 有可能被其它进程删除，忽略出错
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), fileCacheTempPrefix) {
+			return nil
+		}
+		files = append(files, &fileCacheInfo{
+			path:    path,
+			size:    info.Size(),
+			modTime: info.ModTime().UnixNano(),
+		})
+		return nil
+	})
+	return files, err
+}
+
+// evict remove the least recently used files until the size is
+// not more than max bytes, the current file is kept.
+// The directory is scanned as it may be modified by other processes.
+func (s *fileCacheStore) evict(current string) {
+	files, err := s.scan()
+	if err != nil {
+		return
+	}
+	var size int64
+	for _, info := range files {
+		size += info.size
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].modTime < files[j].modTime
+	})
+	for _, info := range files {
+		if size <= s.maxBytes {
+			break
+		}
+		if info.path == current {
+			continue
+		}
+		// 已被其它进程删除的也扣除
+		e := os.Remove(info.path)
+		if e == nil || os.IsNotExist(e) {
+			size -= info.size
+		}
+	}
+	s.size = size
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFileCacheStore(t *testing.T, maxBytes int64) (CacheStore, string) {
+	dir, err := ioutil.TempDir("", "dusk-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFileCacheStore(dir, maxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, dir
+}
+
+func TestFileCacheStore(t *testing.T) {
+	assert := assert.New(t)
+	store, dir := newTestFileCacheStore(t, 0)
+	defer os.RemoveAll(dir)
+
+	_, ok := store.Get("GET http://aslant.site/")
+	assert.False(ok)
+
+	header := make(http.Header)
+	header.Set(HeaderETag, `"abcd"`)
+	expiredAt := time.Now().Add(time.Minute).Round(0)
+	store.Set("GET http://aslant.site/", &CacheEntry{
+		Header:    header,
+		Body:      []byte("abcd"),
+		ETag:      `"abcd"`,
+		ExpiredAt: expiredAt,
+	})
+	entry, ok := store.Get("GET http://aslant.site/")
+	assert.True(ok)
+	assert.Equal(header, entry.Header)
+	assert.Equal([]byte("abcd"), entry.Body)
+	assert.Equal(`"abcd"`, entry.ETag)
+	assert.True(expiredAt.Equal(entry.ExpiredAt))
+
+	// 重新创建仍可读取
+	store, err := NewFileCacheStore(dir, 0)
+	assert.Nil(err)
+	_, ok = store.Get("GET http://aslant.site/")
+	assert.True(ok)
+
+	// 分片目录，无临时文件残留
+	files := make([]string, 0)
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if !info.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	assert.Equal(1, len(files))
+	name := filepath.Base(files[0])
+	assert.Equal(name[:2], filepath.Base(filepath.Dir(files[0])))
+}
+
+func TestFileCacheStoreCorruption(t *testing.T) {
+	assert := assert.New(t)
+	store, dir := newTestFileCacheStore(t, 0)
+	defer os.RemoveAll(dir)
+
+	key := "GET http://aslant.site/"
+	store.Set(key, &CacheEntry{
+		Body: []byte("abcd"),
+	})
+	file := store.(*fileCacheStore).getPath(key)
+	assert.Nil(ioutil.WriteFile(file, []byte("{abcd"), 0600))
+	_, ok := store.Get(key)
+	assert.False(ok)
+	_, err := os.Stat(file)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestFileCacheStoreEvict(t *testing.T) {
+	assert := assert.New(t)
+	clock := &stepClock{
+		now: time.Now(),
+	}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	body := []byte(strings.Repeat("a", 100))
+	store, dir := newTestFileCacheStore(t, 0)
+	defer os.RemoveAll(dir)
+	store.Set("a", &CacheEntry{
+		Body: body,
+	})
+	size := store.(*fileCacheStore).size
+	os.RemoveAll(dir)
+
+	// 最多保存两个
+	store, dir = newTestFileCacheStore(t, 2*size+10)
+	defer os.RemoveAll(dir)
+	for _, key := range []string{"a", "b"} {
+		clock.now = clock.now.Add(time.Second)
+		store.Set(key, &CacheEntry{
+			Body: body,
+		})
+	}
+	// 访问 a，b 为最久未使用
+	clock.now = clock.now.Add(time.Second)
+	_, ok := store.Get("a")
+	assert.True(ok)
+
+	clock.now = clock.now.Add(time.Second)
+	store.Set("c", &CacheEntry{
+		Body: body,
+	})
+	_, ok = store.Get("b")
+	assert.False(ok)
+	for _, key := range []string{"a", "c"} {
+		_, ok = store.Get(key)
+		assert.True(ok, key)
+	}
+	assert.Equal(2*size, store.(*fileCacheStore).size)
+}
+
+func TestFileCacheStoreConcurrent(t *testing.T) {
+	store, dir := newTestFileCacheStore(t, 2048)
+	defer os.RemoveAll(dir)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", i%5)
+			store.Set(key, &CacheEntry{
+				Body: []byte(strings.Repeat("a", 100)),
+			})
+			store.Get(key)
+		}(i)
+	}
+	wg.Wait()
+	assert.True(t, store.(*fileCacheStore).size <= 2048)
+}
+
+func TestFileCacheStoreWithInstance(t *testing.T) {
+	assert := assert.New(t)
+	store, dir := newTestFileCacheStore(t, 0)
+	defer os.RemoveAll(dir)
+
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set(HeaderCacheControl, "max-age=60")
+		w.Write([]byte("abcd"))
+	}))
+	defer ts.Close()
+
+	_, _, err := NewInstance().EnableCache(store).Get(ts.URL).Do()
+	assert.Nil(err)
+	// 新的 store 模拟重启
+	store, err = NewFileCacheStore(dir, 0)
+	assert.Nil(err)
+	d := NewInstance().EnableCache(store).Get(ts.URL)
+	_, body, err := d.Do()
+	assert.Nil(err)
+	assert.Equal("abcd", string(body))
+	assert.Equal(true, d.GetValue(FromCacheValue))
+	assert.Equal(1, hits)
+}
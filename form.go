@@ -0,0 +1,67 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"errors"
+	"net/url"
+)
+
+var (
+	// ErrFormWithData the form fields can only be sent with url.Values
+	ErrFormWithData = errors.New("form fields can only be sent with url.Values data")
+)
+
+// FormField add the field to form, the form is sent as x-www-form-urlencoded,
+// it can be used with Send(url.Values) and the values will be merged.
+func (d *Dusk) FormField(key, value string) *Dusk {
+	if d.form == nil {
+		d.form = make(url.Values)
+	}
+	d.form.Add(key, value)
+	return d
+}
+
+// Form add the fields to form
+func (d *Dusk) Form(fields map[string]string) *Dusk {
+	for k, v := range fields {
+		d.FormField(k, v)
+	}
+	return d
+}
+
+// getSendData get the data to send, the form fields are merged
+// with the url.Values of Send
+func (d *Dusk) getSendData() (interface{}, error) {
+	if len(d.form) == 0 {
+		return d.data, nil
+	}
+	if d.data == nil {
+		return d.form, nil
+	}
+	values, ok := d.data.(url.Values)
+	if !ok {
+		return nil, ErrFormWithData
+	}
+	// 复制数据，避免修改 Send 的参数
+	merged := make(url.Values, len(values)+len(d.form))
+	for k, v := range values {
+		merged[k] = append([]string{}, v...)
+	}
+	for k, v := range d.form {
+		merged[k] = append(merged[k], v...)
+	}
+	return merged, nil
+}
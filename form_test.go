@@ -0,0 +1,61 @@
+package dusk
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForm(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(r.Header.Get(HeaderContentType) + " " + string(buf)))
+	}))
+	defer ts.Close()
+
+	t.Run("form fields", func(t *testing.T) {
+		assert := assert.New(t)
+		_, body, err := Post(ts.URL).
+			FormField("name", "tree xie").
+			FormField("tag", "a").
+			Form(map[string]string{
+				"type": "vip",
+			}).
+			FormField("tag", "b").
+			Do()
+		assert.Nil(err)
+		assert.Equal(MIMEApplicationFormUrlencoded+" name=tree+xie&tag=a&tag=b&type=vip", string(body))
+	})
+
+	t.Run("merge with url values", func(t *testing.T) {
+		assert := assert.New(t)
+		values := url.Values{
+			"name": []string{"tree xie"},
+		}
+		d := Post(ts.URL).
+			Send(values).
+			FormField("name", "vicanso").
+			FormField("type", "vip")
+		_, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal(MIMEApplicationFormUrlencoded+" name=tree+xie&name=vicanso&type=vip", string(body))
+		// 不修改 Send 的参数
+		assert.Equal(1, len(values))
+		assert.Contains(d.ToCurl(), "name=tree+xie&name=vicanso&type=vip")
+	})
+
+	t.Run("conflict with other data", func(t *testing.T) {
+		assert := assert.New(t)
+		_, _, err := Post(ts.URL).
+			Send(map[string]string{
+				"name": "tree xie",
+			}).
+			FormField("type", "vip").
+			Do()
+		assert.Equal(ErrFormWithData, err)
+	})
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+type (
+	// H2StreamStats the stats of a request in batch
+	H2StreamStats struct {
+		// Dusk the request
+		Dusk *Dusk `json:"-"`
+		// Err the error of request
+		Err error `json:"-"`
+		// Conn the identity(local address) of the connection
+		Conn string `json:"conn,omitempty"`
+		// Reused whether the connection is reused
+		Reused bool `json:"reused,omitempty"`
+		// Protocol the protocol of response, e.g. HTTP/2.0
+		Protocol string `json:"protocol,omitempty"`
+		// Duration the duration of request
+		Duration time.Duration `json:"duration,omitempty"`
+	}
+	// H2BatchReport the report of batch requests
+	H2BatchReport struct {
+		// Streams the stats of requests, in the same order as requests
+		Streams []*H2StreamStats `json:"streams,omitempty"`
+		// Connections the count of connections opened
+		Connections int `json:"connections,omitempty"`
+		// StreamsPerConnection the count of streams of each connection
+		StreamsPerConnection map[string]int `json:"streamsPerConnection,omitempty"`
+		// Multiplexed all requests share one http/2 connection,
+		// it's false if http/1.1 is used or the transport opened more than
+		// one connection (e.g. MaxConnsPerHost is not set)
+		Multiplexed bool `json:"multiplexed,omitempty"`
+		// MaxConnsPerHost the MaxConnsPerHost of transport, zero means it's
+		// not set (or the transport isn't *http.Transport), so the transport
+		// may open a new connection before the first one is negotiated
+		MaxConnsPerHost int `json:"maxConnsPerHost,omitempty"`
+		// Total the wall time of all requests
+		Total time.Duration `json:"total,omitempty"`
+	}
+)
+
+// withGotConnTrace add the GotConn trace to context of request,
+// it works with the trace enabled by EnableTrace
+func withGotConnTrace(d *Dusk, stats *H2StreamStats) {
+	ctx := d.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	d.ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			stats.Reused = info.Reused
+			if info.Conn != nil {
+				stats.Conn = info.Conn.LocalAddr().String()
+			}
+		},
+	})
+}
+
+// DoAllH2 do the requests with concurrency(all at once if it's <= 0),
+// and report the connections used by requests, so it can be confirmed
+// whether the requests share one http/2 connection.
+// The listeners and config of instance will be added to the requests
+// if ins isn't nil, so the requests shouldn't be created by the same instance.
+func DoAllH2(ins *Instance, ds []*Dusk, concurrency int) *H2BatchReport {
+	if concurrency <= 0 {
+		concurrency = len(ds)
+	}
+	report := &H2BatchReport{
+		Streams:              make([]*H2StreamStats, len(ds)),
+		StreamsPerConnection: make(map[string]int),
+	}
+	clock := GetClock()
+	if ins != nil && ins.clock != nil {
+		clock = ins.clock
+	}
+	start := clock.Now()
+	limiter := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for index, d := range ds {
+		if ins != nil {
+			ins.init(d)
+		}
+		stats := &H2StreamStats{
+			Dusk: d,
+		}
+		report.Streams[index] = stats
+		withGotConnTrace(d, stats)
+		wg.Add(1)
+		limiter <- struct{}{}
+		go func(d *Dusk) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+			clock := d.getClock()
+			startedAt := clock.Now()
+			resp, _, err := d.Do()
+			stats.Duration = clock.Now().Sub(startedAt)
+			stats.Err = err
+			if resp != nil {
+				stats.Protocol = resp.Proto
+			}
+		}(d)
+	}
+	wg.Wait()
+	report.Total = clock.Now().Sub(start)
+
+	for _, stats := range report.Streams {
+		// 请求失败时有可能未获取到连接
+		if stats.Conn == "" {
+			continue
+		}
+		report.StreamsPerConnection[stats.Conn]++
+	}
+	report.Connections = len(report.StreamsPerConnection)
+	report.Multiplexed = report.Connections == 1
+	for _, stats := range report.Streams {
+		if stats.Conn != "" && stats.Protocol != "HTTP/2.0" {
+			report.Multiplexed = false
+		}
+	}
+	if len(ds) != 0 {
+		report.MaxConnsPerHost = getMaxConnsPerHost(ds[0])
+	}
+	return report
+}
+
+// getMaxConnsPerHost get the MaxConnsPerHost of the transport of request
+func getMaxConnsPerHost(d *Dusk) int {
+	transport := getClient(d).Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	t, ok := transport.(*http.Transport)
+	if !ok {
+		return 0
+	}
+	return t.MaxConnsPerHost
+}
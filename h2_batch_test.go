@@ -0,0 +1,126 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoAllH2(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	t.Run("multiplexed", func(t *testing.T) {
+		assert := assert.New(t)
+		client := ts.Client()
+		// 先建立连接，保证后续的请求复用此连接
+		_, body, err := Get(ts.URL).SetClient(client).Do()
+		assert.Nil(err)
+		assert.Equal("HTTP/2.0", string(body))
+
+		count := 50
+		ds := make([]*Dusk, count)
+		for i := 0; i < count; i++ {
+			ds[i] = Get(ts.URL).SetClient(client)
+		}
+		ins := NewInstance()
+		var done int32
+		ins.AddDoneListener(func(_ *Dusk) error {
+			atomic.AddInt32(&done, 1)
+			return nil
+		})
+		report := DoAllH2(ins, ds, 10)
+		assert.Equal(int32(count), done)
+		assert.Equal(count, len(report.Streams))
+		assert.True(report.Multiplexed)
+		assert.Equal(1, report.Connections)
+		assert.Equal(0, report.MaxConnsPerHost)
+		for _, stats := range report.Streams {
+			assert.Nil(stats.Err)
+			assert.Equal("HTTP/2.0", stats.Protocol)
+			assert.True(stats.Reused)
+			assert.Equal(count, report.StreamsPerConnection[stats.Conn])
+		}
+		assert.True(report.Total > 0)
+	})
+
+	t.Run("multiple connections", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 5
+		ds := make([]*Dusk, count)
+		for i := 0; i < count; i++ {
+			// 每个请求使用新的 client（transport），无法复用连接
+			client := ts.Client()
+			transport := client.Transport.(*http.Transport).Clone()
+			ds[i] = Get(ts.URL).SetClient(&http.Client{
+				Transport: transport,
+			})
+		}
+		report := DoAllH2(nil, ds, 0)
+		assert.False(report.Multiplexed)
+		assert.Equal(count, report.Connections)
+	})
+
+	t.Run("http/1.1 keep alive", func(t *testing.T) {
+		assert := assert.New(t)
+		h1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}))
+		defer h1.Close()
+		transport := h1.Client().Transport.(*http.Transport).Clone()
+		transport.MaxConnsPerHost = 1
+		client := &http.Client{
+			Transport: transport,
+		}
+		ds := []*Dusk{
+			Get(h1.URL).SetClient(client),
+			Get(h1.URL).SetClient(client),
+		}
+		// 串行请求复用同一连接，但非 http/2
+		report := DoAllH2(nil, ds, 1)
+		assert.Equal(1, report.Connections)
+		assert.False(report.Multiplexed)
+		assert.Equal(1, report.MaxConnsPerHost)
+	})
+
+	t.Run("clock of instance", func(t *testing.T) {
+		assert := assert.New(t)
+		client := ts.Client()
+		ds := []*Dusk{
+			Get(ts.URL).SetClient(client),
+			Get(ts.URL).SetClient(client),
+		}
+		// 时间不变的 clock，耗时均为 0
+		ins := NewInstance().SetClock(&stepClock{
+			now: time.Now(),
+		})
+		report := DoAllH2(ins, ds, 0)
+		for _, stats := range report.Streams {
+			assert.Nil(stats.Err)
+			assert.Equal(time.Duration(0), stats.Duration)
+		}
+		assert.Equal(time.Duration(0), report.Total)
+	})
+}
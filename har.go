@@ -0,0 +1,282 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+const (
+	harHTTPVersion = "HTTP/1.1"
+	// harNotApplicable the timing is not applicable for the request
+	harNotApplicable = -1
+)
+
+type (
+	// HARNameValue the name and value of header, query or cookie
+	HARNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	// HARRequest the request of har entry
+	HARRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []HARNameValue `json:"cookies"`
+		Headers     []HARNameValue `json:"headers"`
+		QueryString []HARNameValue `json:"queryString"`
+		PostData    *HARPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+	// HARPostData the post data of har request
+	HARPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+	// HARContent the content of har response
+	HARContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+	}
+	// HARResponse the response of har entry
+	HARResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []HARNameValue `json:"cookies"`
+		Headers     []HARNameValue `json:"headers"`
+		Content     HARContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+	// HARCache the cache of har entry
+	HARCache struct{}
+	// HARTimings the timings of har entry, the unit is millisecond
+	// and -1 means the timing is not applicable
+	HARTimings struct {
+		Blocked float64 `json:"blocked"`
+		DNS     float64 `json:"dns"`
+		Connect float64 `json:"connect"`
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+		SSL     float64 `json:"ssl"`
+	}
+	// HAREntry the entry of har 1.2
+	HAREntry struct {
+		StartedDateTime string      `json:"startedDateTime,omitempty"`
+		Time            float64     `json:"time"`
+		Request         HARRequest  `json:"request"`
+		Response        HARResponse `json:"response"`
+		Cache           HARCache    `json:"cache"`
+		Timings         HARTimings  `json:"timings"`
+	}
+)
+
+// toHARMilliseconds convert duration to milliseconds
+func toHARMilliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// toHAROptionalMilliseconds convert duration to milliseconds,
+// it returns -1 if the duration is 0(e.g. the connection is reused)
+func toHAROptionalMilliseconds(d time.Duration) float64 {
+	if d == 0 {
+		return harNotApplicable
+	}
+	return toHARMilliseconds(d)
+}
+
+// newHARNameValues convert the values to har name values sorted by name
+func newHARNameValues(values map[string][]string) []HARNameValue {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]HARNameValue, 0)
+	for _, name := range names {
+		for _, value := range values[name] {
+			result = append(result, HARNameValue{
+				Name:  name,
+				Value: value,
+			})
+		}
+	}
+	return result
+}
+
+// newHARCookies convert the cookies to har name values,
+// the values of cookies are redacted as Cookie and Set-Cookie headers
+func newHARCookies(cookies []*http.Cookie) []HARNameValue {
+	result := make([]HARNameValue, len(cookies))
+	for i, c := range cookies {
+		result[i] = HARNameValue{
+			Name:  c.Name,
+			Value: redactedValue,
+		}
+	}
+	return result
+}
+
+// newHARTimings convert the stats to har timings
+func newHARTimings(stats *HTTPTimelineStats) HARTimings {
+	// har 中 connect 包括 ssl 的时间
+	connect := stats.TCPConnection + stats.TLSHandshake
+	// server processing 为 got connect 至首字节的时间，包括了发送请求的时间
+	wait := stats.ServerProcessing - stats.RequestWrite
+	if wait < 0 {
+		wait = 0
+	}
+	return HARTimings{
+		Blocked: harNotApplicable,
+		DNS:     toHAROptionalMilliseconds(stats.DNSLookup),
+		Connect: toHAROptionalMilliseconds(connect),
+		Send:    toHARMilliseconds(stats.RequestWrite),
+		Wait:    toHARMilliseconds(wait),
+		Receive: toHARMilliseconds(stats.ContentTransfer),
+		SSL:     toHAROptionalMilliseconds(stats.TLSHandshake),
+	}
+}
+
+// ToHAR convert the stats to har 1.2 entry,
+// it can be imported to chrome devtools for visual analysis.
+func (stats *HTTPTimelineStats) ToHAR(requestURL, method string, statusCode int) ([]byte, error) {
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return nil, err
+	}
+	entry := &HAREntry{
+		Time: toHARMilliseconds(stats.Total),
+		Request: HARRequest{
+			Method:      method,
+			URL:         requestURL,
+			HTTPVersion: harHTTPVersion,
+			Cookies:     make([]HARNameValue, 0),
+			Headers:     make([]HARNameValue, 0),
+			QueryString: newHARNameValues(u.Query()),
+			HeadersSize: harNotApplicable,
+			BodySize:    harNotApplicable,
+		},
+		Response: HARResponse{
+			Status:      statusCode,
+			StatusText:  http.StatusText(statusCode),
+			HTTPVersion: harHTTPVersion,
+			Cookies:     make([]HARNameValue, 0),
+			Headers:     make([]HARNameValue, 0),
+			Content: HARContent{
+				Size: harNotApplicable,
+			},
+			HeadersSize: harNotApplicable,
+			BodySize:    harNotApplicable,
+		},
+		Timings: newHARTimings(stats),
+	}
+	return json.Marshal(entry)
+}
+
+// ToHAR convert the request and response to har 1.2 entry,
+// the timings will be -1 if the trace isn't enabled.
+// The sensitive headers(DefaultSensitiveHeaders, Set-Cookie and
+// the headers added by RedactHeader) and cookies are redacted.
+func (d *Dusk) ToHAR() *HAREntry {
+	redactedHeaders := append(append([]string{"Set-Cookie"}, DefaultSensitiveHeaders...), d.GetRedactedHeaders()...)
+	entry := &HAREntry{
+		Timings: HARTimings{
+			Blocked: harNotApplicable,
+			DNS:     harNotApplicable,
+			Connect: harNotApplicable,
+			Send:    harNotApplicable,
+			Wait:    harNotApplicable,
+			Receive: harNotApplicable,
+			SSL:     harNotApplicable,
+		},
+	}
+	if d.ht != nil {
+		stats := d.ht.Stats()
+		entry.StartedDateTime = d.ht.Start.Format(time.RFC3339Nano)
+		entry.Time = toHARMilliseconds(stats.Total)
+		entry.Timings = newHARTimings(stats)
+	}
+
+	harReq := HARRequest{
+		Method:      d.GetMethod(),
+		URL:         d.GetURL(),
+		HTTPVersion: harHTTPVersion,
+		Cookies:     make([]HARNameValue, 0),
+		Headers:     make([]HARNameValue, 0),
+		QueryString: make([]HARNameValue, 0),
+		HeadersSize: harNotApplicable,
+		BodySize:    harNotApplicable,
+	}
+	if req := d.Request; req != nil {
+		harReq.URL = req.URL.String()
+		harReq.HTTPVersion = req.Proto
+		harReq.Cookies = newHARCookies(req.Cookies())
+		harReq.Headers = newHARNameValues(redactHeader(req.Header, redactedHeaders...))
+		harReq.QueryString = newHARNameValues(req.URL.Query())
+		if req.GetBody != nil {
+			r, err := req.GetBody()
+			if err == nil {
+				buf, _ := ioutil.ReadAll(r)
+				r.Close()
+				harReq.BodySize = len(buf)
+				harReq.PostData = &HARPostData{
+					MimeType: req.Header.Get(HeaderContentType),
+					Text:     string(buf),
+				}
+			}
+		} else if req.Body == nil || req.Body == http.NoBody {
+			harReq.BodySize = 0
+		}
+	}
+	entry.Request = harReq
+
+	harResp := HARResponse{
+		HTTPVersion: harHTTPVersion,
+		Cookies:     make([]HARNameValue, 0),
+		Headers:     make([]HARNameValue, 0),
+		Content: HARContent{
+			Size: harNotApplicable,
+		},
+		HeadersSize: harNotApplicable,
+		BodySize:    harNotApplicable,
+	}
+	if resp := d.Response; resp != nil {
+		harResp.Status = resp.StatusCode
+		harResp.StatusText = http.StatusText(resp.StatusCode)
+		if resp.Proto != "" {
+			harResp.HTTPVersion = resp.Proto
+		}
+		harResp.Cookies = newHARCookies(resp.Cookies())
+		harResp.Headers = newHARNameValues(redactHeader(resp.Header, redactedHeaders...))
+		harResp.Content = HARContent{
+			Size:     len(d.Body),
+			MimeType: resp.Header.Get(HeaderContentType),
+		}
+		harResp.RedirectURL = resp.Header.Get(HeaderLocation)
+		harResp.BodySize = len(d.Body)
+	}
+	entry.Response = harResp
+	return entry
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func TestToHAR(t *testing.T) {
+	assert := assert.New(t)
+	stats := &HTTPTimelineStats{
+		DNSLookup:        5 * time.Millisecond,
+		TCPConnection:    10 * time.Millisecond,
+		TLSHandshake:     20 * time.Millisecond,
+		RequestWrite:     time.Millisecond,
+		ServerProcessing: 31 * time.Millisecond,
+		ContentTransfer:  4 * time.Millisecond,
+		Total:            70 * time.Millisecond,
+	}
+	buf, err := stats.ToHAR("https://aslant.site/?id=1", "GET", 200)
+	assert.Nil(err)
+
+	m := make(map[string]interface{})
+	err = json.Unmarshal(buf, &m)
+	assert.Nil(err)
+	timings := m["timings"].(map[string]interface{})
+	for _, key := range []string{
+		"dns",
+		"connect",
+		"send",
+		"wait",
+		"receive",
+	} {
+		_, ok := timings[key]
+		assert.True(ok, key+" should be exists")
+	}
+
+	entry := HAREntry{}
+	err = json.Unmarshal(buf, &entry)
+	assert.Nil(err)
+	assert.Equal(float64(70), entry.Time)
+	assert.Equal("GET", entry.Request.Method)
+	assert.Equal("https://aslant.site/?id=1", entry.Request.URL)
+	assert.Equal([]HARNameValue{
+		{
+			Name:  "id",
+			Value: "1",
+		},
+	}, entry.Request.QueryString)
+	assert.Equal(200, entry.Response.Status)
+	assert.Equal("OK", entry.Response.StatusText)
+	assert.Equal(HARTimings{
+		Blocked: -1,
+		DNS:     5,
+		Connect: 30,
+		Send:    1,
+		Wait:    30,
+		Receive: 4,
+		SSL:     20,
+	}, entry.Timings)
+
+	// 连接复用时 dns 与 connect 不适用
+	buf, err = (&HTTPTimelineStats{
+		ServerProcessing: time.Millisecond,
+	}).ToHAR("https://aslant.site/", "GET", 200)
+	assert.Nil(err)
+	entry = HAREntry{}
+	err = json.Unmarshal(buf, &entry)
+	assert.Nil(err)
+	assert.Equal(float64(-1), entry.Timings.DNS)
+	assert.Equal(float64(-1), entry.Timings.Connect)
+	assert.Equal(float64(1), entry.Timings.Wait)
+}
+
+func TestDuskToHAR(t *testing.T) {
+	assert := assert.New(t)
+	defer gock.Off()
+	gock.New("http://aslant.site").
+		Post("/users").
+		Reply(201).
+		SetHeader("X-Response-Id", "1").
+		SetHeader("Set-Cookie", "jt=def").
+		JSON(map[string]string{
+			"name": "tree.xie",
+		})
+
+	d := Post("http://aslant.site/users").
+		Query("type", "vip").
+		AddCookie("jt", "abc").
+		Set("Authorization", "Bearer abc").
+		Set("X-Token", "abc").
+		RedactHeader("X-Token").
+		Send(map[string]string{
+			"name": "tree.xie",
+		})
+	_, _, err := d.Do()
+	assert.Nil(err)
+	buf, err := json.MarshalIndent(d.ToHAR(), "", "  ")
+	assert.Nil(err)
+
+	file := filepath.Join("testdata", "har.golden")
+	if *updateGolden {
+		err = ioutil.WriteFile(file, buf, 0644)
+		assert.Nil(err)
+	}
+	expected, err := ioutil.ReadFile(file)
+	assert.Nil(err)
+	assert.Equal(string(expected), string(buf))
+}
+
+func TestDuskToHARWithTrace(t *testing.T) {
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	d := Get(ts.URL).EnableTrace()
+	_, _, err := d.Do()
+	assert.Nil(err)
+	entry := d.ToHAR()
+	assert.NotEmpty(entry.StartedDateTime)
+	assert.True(entry.Time > 0)
+	assert.True(entry.Timings.Wait >= 0)
+	assert.True(entry.Timings.Receive >= 0)
+	assert.Equal(float64(-1), entry.Timings.SSL)
+	assert.Equal(4, entry.Response.BodySize)
+	assert.Equal(0, entry.Request.BodySize)
+}
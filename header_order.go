@@ -0,0 +1,322 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrHeaderOrderUnsupported header order is unsupported for the protocol
+	ErrHeaderOrderUnsupported = errors.New("header order is only supported for http/1.1")
+	// ErrHeaderOrderProxy header order is unsupported with proxy
+	ErrHeaderOrderProxy = errors.New("header order is not supported with proxy")
+	// ErrHeaderInvalid the name or value of header is invalid
+	ErrHeaderInvalid = errors.New("header is invalid")
+)
+
+type (
+	// headerOrderTransport the transport writes http/1.1 request with ordered header,
+	// the connection won't be reused.
+	headerOrderTransport struct {
+		order     []string
+		tlsConfig *tls.Config
+		// 以下为 client transport 的配置
+		dialContext           func(ctx context.Context, network, addr string) (net.Conn, error)
+		proxy                 func(*http.Request) (*url.URL, error)
+		tlsHandshakeTimeout   time.Duration
+		responseHeaderTimeout time.Duration
+	}
+	connCloser struct {
+		io.ReadCloser
+		conn net.Conn
+		// stop 取消 context 完成时关闭连接的函数，避免 context 长期有效时无法释放
+		stop func() bool
+	}
+)
+
+func (cc *connCloser) Close() error {
+	cc.stop()
+	err := cc.ReadCloser.Close()
+	cc.conn.Close()
+	return err
+}
+
+// HeaderOrder set the order of request header,
+// the request will be written by a http/1.1 only transport,
+// so the connection won't be reused and the request with
+// h2 protocol will get ErrHeaderOrderUnsupported.
+// The dialer, tls handshake and response header timeout of transport are used,
+// but the request via proxy will get ErrHeaderOrderProxy.
+func (d *Dusk) HeaderOrder(keys ...string) *Dusk {
+	d.headerOrder = keys
+	return d
+}
+
+func newHeaderOrderTransport(c *http.Client, order []string) *headerOrderTransport {
+	ht := &headerOrderTransport{
+		order: order,
+	}
+	rt := c.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if t, ok := rt.(*http.Transport); ok {
+		if t.TLSClientConfig != nil {
+			ht.tlsConfig = t.TLSClientConfig.Clone()
+		}
+		ht.dialContext = t.DialContext
+		ht.proxy = t.Proxy
+		ht.tlsHandshakeTimeout = t.TLSHandshakeTimeout
+		ht.responseHeaderTimeout = t.ResponseHeaderTimeout
+	}
+	if ht.tlsConfig == nil {
+		ht.tlsConfig = &tls.Config{}
+	}
+	// 只支持 http/1.1
+	ht.tlsConfig.NextProtos = []string{"http/1.1"}
+	return ht
+}
+
+func (t *headerOrderTransport) dial(ctx context.Context, req *http.Request) (conn net.Conn, err error) {
+	host := req.URL.Hostname()
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	// 有序的请求头直接写入连接，无法经代理发送
+	if t.proxy != nil {
+		proxyURL, e := t.proxy(req)
+		if e != nil {
+			err = e
+			return
+		}
+		if proxyURL != nil {
+			err = ErrHeaderOrderProxy
+			return
+		}
+	}
+	dialContext := t.dialContext
+	if dialContext == nil {
+		dialContext = (&net.Dialer{}).DialContext
+	}
+	conn, err = dialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil || req.URL.Scheme != "https" {
+		return
+	}
+	tlsConfig := t.tlsConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	handshakeCtx := ctx
+	if t.tlsHandshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		handshakeCtx, cancel = context.WithTimeout(ctx, t.tlsHandshakeTimeout)
+		defer cancel()
+	}
+	err = tlsConn.HandshakeContext(handshakeCtx)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	conn = tlsConn
+	return
+}
+
+// sortHeaderKeys sort the keys of header, the keys in order will be first
+func sortHeaderKeys(header http.Header, order []string) []string {
+	keys := make([]string, 0, len(header))
+	exists := make(map[string]bool)
+	for _, key := range order {
+		key = textproto.CanonicalMIMEHeaderKey(key)
+		if _, ok := header[key]; ok && !exists[key] {
+			exists[key] = true
+			keys = append(keys, key)
+		}
+	}
+	others := make([]string, 0, len(header))
+	for key := range header {
+		if !exists[key] {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	return append(keys, others...)
+}
+
+// isTokenChar check the char is valid for token(RFC 7230)
+func isTokenChar(c byte) bool {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) != -1
+}
+
+// validHeaderFieldName check the name of header is a valid token
+func validHeaderFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isTokenChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// validHeaderFieldValue check the value of header has no control
+// characters(except horizontal tab), such as CR and LF
+func validHeaderFieldValue(value string) bool {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if (c < ' ' && c != '\t') || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *headerOrderTransport) writeRequest(w io.Writer, req *http.Request) (err error) {
+	var body []byte
+	if req.Body != nil {
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return
+		}
+	}
+	// 请求头直接写入连接，需要校验避免注入
+	if !validHeaderFieldName(req.Method) {
+		return fmt.Errorf("%w: method %q", ErrHeaderInvalid, req.Method)
+	}
+	for key, values := range req.Header {
+		if !validHeaderFieldName(key) {
+			return fmt.Errorf("%w: name %q", ErrHeaderInvalid, key)
+		}
+		for _, value := range values {
+			if !validHeaderFieldValue(value) {
+				return fmt.Errorf("%w: value of %s", ErrHeaderInvalid, key)
+			}
+		}
+	}
+	if !validHeaderFieldValue(req.Host) {
+		return fmt.Errorf("%w: host %q", ErrHeaderInvalid, req.Host)
+	}
+	header := req.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	header.Set("Host", host)
+	if header.Get("User-Agent") == "" {
+		header.Set("User-Agent", "Go-http-client/1.1")
+	}
+	if len(body) != 0 || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		header.Set(HeaderContentLength, strconv.Itoa(len(body)))
+	}
+	header.Set("Connection", "close")
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+	// host 必须为首个请求头
+	order := append([]string{"Host"}, t.order...)
+	for _, key := range sortHeaderKeys(header, order) {
+		for _, value := range header[key] {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, strings.TrimSpace(value))
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	_, err = w.Write(buf.Bytes())
+	return
+}
+
+// RoundTrip write the request with ordered header and read response
+func (t *headerOrderTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	if req.ProtoMajor > 1 {
+		err = ErrHeaderOrderUnsupported
+		return
+	}
+	ctx := req.Context()
+	conn, err := t.dial(ctx, req)
+	if err != nil {
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer func() {
+		// 如果出错，则关闭连接
+		if err != nil {
+			stop()
+			conn.Close()
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+		}
+	}()
+	err = t.writeRequest(conn, req)
+	if err != nil {
+		return
+	}
+	deadline, hasDeadline := ctx.Deadline()
+	if t.responseHeaderTimeout > 0 {
+		// 等待响应头的超时，不晚于 context 的超时
+		headerDeadline := time.Now().Add(t.responseHeaderTimeout)
+		if !hasDeadline || headerDeadline.Before(deadline) {
+			conn.SetReadDeadline(headerDeadline)
+		}
+	}
+	resp, err = http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return
+	}
+	if t.responseHeaderTimeout > 0 {
+		// 读取响应体时恢复为 context 的超时
+		conn.SetReadDeadline(deadline)
+	}
+	resp.Body = &connCloser{
+		ReadCloser: resp.Body,
+		conn:       conn,
+		stop:       stop,
+	}
+	return
+}
@@ -0,0 +1,186 @@
+package dusk
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortHeaderKeys(t *testing.T) {
+	assert := assert.New(t)
+	header := make(http.Header)
+	header.Set("X-Request-Id", "1")
+	header.Set("Accept", "*/*")
+	header.Set("User-Agent", "dusk")
+	header.Set("A", "1")
+	assert.Equal([]string{
+		"User-Agent",
+		"Accept",
+		"A",
+		"X-Request-Id",
+	}, sortHeaderKeys(header, []string{"user-agent", "Accept", "Not-Exists"}))
+}
+
+func TestHeaderOrder(t *testing.T) {
+	assert := assert.New(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		lines := make([]string, 0)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				break
+			}
+			lines = append(lines, line)
+		}
+		body := strings.Join(lines, "\n")
+		fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	}()
+
+	_, body, err := Get("http://"+ln.Addr().String()+"/users/me").
+		Query("type", "1").
+		Set("X-Request-Id", "123").
+		Set("Accept", "application/json").
+		Set("User-Agent", "dusk").
+		HeaderOrder("User-Agent", "Accept").
+		Do()
+	assert.Nil(err)
+	assert.Equal(strings.Join([]string{
+		"GET /users/me?type=1 HTTP/1.1",
+		"Host: " + ln.Addr().String(),
+		"User-Agent: dusk",
+		"Accept: application/json",
+		"Connection: close",
+		"X-Request-Id: 123",
+	}, "\n"), string(body))
+}
+
+func TestHeaderOrderStopAfterFunc(t *testing.T) {
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+	assert.Nil(err)
+	rt := newHeaderOrderTransport(http.DefaultClient, []string{"User-Agent"})
+	resp, err := rt.RoundTrip(req)
+	assert.Nil(err)
+	cc, ok := resp.Body.(*connCloser)
+	assert.True(ok)
+	assert.Nil(resp.Body.Close())
+	// 关闭后已取消，context 未完成时也不再保留
+	assert.False(cc.stop())
+}
+
+func TestHeaderOrderInvalidHeader(t *testing.T) {
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Evil")))
+	}))
+	defer ts.Close()
+
+	for _, header := range []http.Header{
+		{"X-Value": []string{"a\r\nX-Evil: 1"}},
+		{"X-Value": []string{"a\nX-Evil: 1"}},
+		{"X-Evil: 1\r\nX-Name": []string{"a"}},
+	} {
+		d := Get(ts.URL).HeaderOrder("X-Value")
+		d.header = header
+		_, _, err := d.Do()
+		assert.True(errors.Is(err, ErrHeaderInvalid))
+	}
+	assert.True(validHeaderFieldValue("application/json;\tq=0.9"))
+}
+
+func TestHeaderOrderTransportSettings(t *testing.T) {
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	var dials int32
+	dialer := &net.Dialer{}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				atomic.AddInt32(&dials, 1)
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+	_, body, err := Get(ts.URL).
+		SetClient(client).
+		HeaderOrder("Accept").
+		Do()
+	assert.Nil(err)
+	assert.Equal("ok", string(body))
+	assert.Equal(int32(1), atomic.LoadInt32(&dials))
+
+	// local addr 的 dialer
+	_, body, err = Get(ts.URL).
+		LocalAddr("127.0.0.1").
+		HeaderOrder("Accept").
+		Do()
+	assert.Nil(err)
+	assert.Equal("ok", string(body))
+
+	_, _, err = Get(ts.URL).
+		Proxy("http://127.0.0.1:1").
+		HeaderOrder("Accept").
+		Do()
+	assert.True(errors.Is(err, ErrHeaderOrderProxy))
+}
+
+func TestHeaderOrderResponseHeaderTimeout(t *testing.T) {
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("slow") != "" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	_, _, err := Get(ts.URL).
+		Query("slow", "1").
+		Timeouts(0, 0, 50*time.Millisecond, 0).
+		HeaderOrder("Accept").
+		Do()
+	var netErr net.Error
+	assert.True(errors.As(err, &netErr) && netErr.Timeout())
+
+	// 读取响应头后不再受其超时限制
+	_, body, err := Get(ts.URL).
+		Timeouts(0, 0, 50*time.Millisecond, time.Second).
+		HeaderOrder("Accept").
+		Do()
+	assert.Nil(err)
+	assert.Equal("ok", string(body))
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const (
+	// PhaseHeaderPolicy header policy phase
+	PhaseHeaderPolicy LifecyclePhase = "header-policy"
+)
+
+type (
+	// HeaderRules the headers to strip or require
+	HeaderRules struct {
+		// Strip the headers will be removed from request
+		Strip []string
+		// Require the headers should be set, otherwise the request fails
+		Require []string
+	}
+	// HeaderPolicy the policy of outbound request headers,
+	// the rules are chosen by whether the host of request is internal
+	HeaderPolicy struct {
+		// InternalHosts the patterns of internal host(path.Match syntax),
+		// e.g. *.svc.cluster.local or 10.0.*
+		InternalHosts []string
+		// Internal the rules for internal host
+		Internal HeaderRules
+		// External the rules for external host
+		External HeaderRules
+	}
+	// HeaderPolicyError the required header is not set
+	HeaderPolicyError struct {
+		// Host the host of request
+		Host string
+		// Header the required header
+		Header string
+	}
+)
+
+func (e *HeaderPolicyError) Error() string {
+	return fmt.Sprintf("header policy: %s is required for host %s", e.Header, e.Host)
+}
+
+// isInternal check whether the host matches the internal patterns
+func (p *HeaderPolicy) isInternal(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range p.InternalHosts {
+		matched, _ := path.Match(strings.ToLower(pattern), host)
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// apply strip the headers and check the required headers of request,
+// it returns the stripped headers
+func (p *HeaderPolicy) apply(req *http.Request) (stripped []string, err error) {
+	host := req.URL.Hostname()
+	rules := p.External
+	if p.isInternal(host) {
+		rules = p.Internal
+	}
+	for _, key := range rules.Strip {
+		if _, ok := req.Header[http.CanonicalHeaderKey(key)]; ok {
+			req.Header.Del(key)
+			stripped = append(stripped, http.CanonicalHeaderKey(key))
+		}
+	}
+	for _, key := range rules.Require {
+		if req.Header.Get(key) == "" {
+			err = &HeaderPolicyError{
+				Host:   host,
+				Header: http.CanonicalHeaderKey(key),
+			}
+			return
+		}
+	}
+	return
+}
+
+// applyHeaderPolicies apply the header policies to request,
+// the stripped headers are recorded in event log
+func (d *Dusk) applyHeaderPolicies(req *http.Request) error {
+	for _, p := range d.headerPolicies {
+		stripped, err := p.apply(req)
+		if len(stripped) != 0 && d.isEventLogEnabled() {
+			d.logEvent(PhaseHeaderPolicy, fmt.Sprintf("stripped %s for %s", strings.Join(stripped, ","), req.URL.Host))
+		}
+		if err != nil {
+			d.logEventError(PhaseHeaderPolicy, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// HeaderPolicy add the header policy to request, it's applied after
+// all request before listeners, so it sees the final header set,
+// and it's also applied to the redirected requests.
+func (d *Dusk) HeaderPolicy(policy HeaderPolicy) *Dusk {
+	d.headerPolicies = append(d.headerPolicies, &policy)
+	return d
+}
+
+// HeaderPolicy add the header policy for all requests of instance,
+// the stripped headers are recorded if the event log of request is enabled.
+func (ins *Instance) HeaderPolicy(policy HeaderPolicy) *Instance {
+	ins.headerPolicies = append(ins.headerPolicies, &policy)
+	return ins
+}
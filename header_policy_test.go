@@ -0,0 +1,95 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderPolicy(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Internal-Auth") + r.Header.Get("X-Debug")))
+	}))
+	defer external.Close()
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			// 重定向至外部服务
+			http.Redirect(w, r, strings.Replace(external.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+			return
+		}
+		w.Write([]byte(r.Header.Get("X-Internal-Auth")))
+	}))
+	defer internal.Close()
+
+	policy := HeaderPolicy{
+		InternalHosts: []string{"127.0.0.*"},
+		Internal: HeaderRules{
+			Require: []string{"X-Internal-Auth"},
+		},
+		External: HeaderRules{
+			Strip: []string{"X-Internal-Auth", "x-debug"},
+		},
+	}
+	newInstance := func() *Instance {
+		ins := NewInstance().HeaderPolicy(policy)
+		// 在 policy 之后添加的请求头同样会被检查
+		ins.AddRequestListener(func(req *http.Request, _ *Dusk) error {
+			req.Header.Set("X-Debug", "1")
+			return nil
+		}, EventTypeBefore)
+		return ins
+	}
+
+	t.Run("internal host", func(t *testing.T) {
+		assert := assert.New(t)
+		_, body, err := newInstance().Get(internal.URL).
+			Set("X-Internal-Auth", "abc").
+			Do()
+		assert.Nil(err)
+		assert.Equal("abc", string(body))
+
+		_, _, err = newInstance().Get(internal.URL).Do()
+		policyErr := &HeaderPolicyError{}
+		assert.True(errors.As(err, &policyErr))
+		assert.Equal("X-Internal-Auth", policyErr.Header)
+		assert.Equal("header policy: X-Internal-Auth is required for host 127.0.0.1", err.Error())
+	})
+
+	t.Run("strip for external host", func(t *testing.T) {
+		assert := assert.New(t)
+		d := newInstance().Get(internal.URL+"/redirect").
+			Set("X-Internal-Auth", "abc").
+			EnableEventLog()
+		_, body, err := d.Do()
+		assert.Nil(err)
+		assert.Empty(string(body))
+		assert.Contains(d.EventLog().String(), "header-policy")
+		assert.Contains(d.EventLog().String(), "stripped X-Internal-Auth,X-Debug for localhost")
+
+		// 未启用时不记录
+		d = newInstance().Get(internal.URL+"/redirect").
+			Set("X-Internal-Auth", "abc")
+		_, body, err = d.Do()
+		assert.Nil(err)
+		assert.Empty(string(body))
+		assert.Nil(d.EventLog())
+	})
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type (
+	// HealthStatus the result of healthcheck
+	HealthStatus struct {
+		// Available whether the service responds
+		Available bool
+		// Latency the duration of healthcheck request
+		Latency time.Duration
+		// StatusCode the status code of response, 0 if no response
+		StatusCode int
+		// Error the error of healthcheck request
+		Error error
+	}
+)
+
+// IsHealthy check whether the service is healthy,
+// it responds and the status code is less than 500
+func (s HealthStatus) IsHealthy() bool {
+	return s.Available && s.StatusCode < http.StatusInternalServerError
+}
+
+// Healthcheck get the path(base url of config is prepended)
+// and return the health status of service
+func (ins *Instance) Healthcheck(ctx context.Context, path string) HealthStatus {
+	d := ins.Get(path).SetContext(ctx)
+	clock := d.getClock()
+	startedAt := clock.Now()
+	resp, _, err := d.Do()
+	status := HealthStatus{
+		Latency: clock.Now().Sub(startedAt),
+		Error:   err,
+	}
+	// 有响应则表示服务可用（状态码由 IsHealthy 判断）
+	if resp != nil {
+		status.Available = true
+		status.StatusCode = resp.StatusCode
+	}
+	return status
+}
+
+// StartPeriodicHealthcheck run the healthcheck in goroutine every interval
+// until the context is done, the first check is run immediately.
+func (ins *Instance) StartPeriodicHealthcheck(ctx context.Context, interval time.Duration, path string, onResult func(HealthStatus)) {
+	clock := ins.clock
+	if clock == nil {
+		clock = GetClock()
+	}
+	go func() {
+		for {
+			status := ins.Healthcheck(ctx, path)
+			// context 结束时的检测结果不再回调
+			if ctx.Err() != nil {
+				return
+			}
+			onResult(status)
+			if clock.Sleep(ctx, interval) != nil {
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthStatus(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(HealthStatus{
+		Available:  true,
+		StatusCode: 204,
+	}.IsHealthy())
+	assert.True(HealthStatus{
+		Available:  true,
+		StatusCode: 404,
+	}.IsHealthy())
+	assert.False(HealthStatus{
+		Available:  true,
+		StatusCode: 503,
+	}.IsHealthy())
+	assert.False(HealthStatus{}.IsHealthy())
+}
+
+func TestHealthcheck(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/unavailable" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	ins := NewInstanceWithConfig(Config{
+		BaseURL: ts.URL,
+	})
+
+	t.Run("healthy", func(t *testing.T) {
+		assert := assert.New(t)
+		status := ins.Healthcheck(context.Background(), "/ping")
+		assert.True(status.IsHealthy())
+		assert.Equal(http.StatusNoContent, status.StatusCode)
+		assert.Nil(status.Error)
+		assert.True(status.Latency >= 5*time.Millisecond)
+	})
+
+	t.Run("unhealthy", func(t *testing.T) {
+		assert := assert.New(t)
+		status := ins.Healthcheck(context.Background(), "/unavailable")
+		assert.True(status.Available)
+		assert.False(status.IsHealthy())
+		assert.Equal(http.StatusServiceUnavailable, status.StatusCode)
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		assert := assert.New(t)
+		closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		closed.Close()
+		status := NewInstanceWithConfig(Config{
+			BaseURL: closed.URL,
+		}).Healthcheck(context.Background(), "/ping")
+		assert.False(status.Available)
+		assert.False(status.IsHealthy())
+		assert.NotNil(status.Error)
+	})
+}
+
+func TestStartPeriodicHealthcheck(t *testing.T) {
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	ins := NewInstanceWithConfig(Config{
+		BaseURL: ts.URL,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan HealthStatus, 10)
+	ins.StartPeriodicHealthcheck(ctx, time.Millisecond, "/ping", func(status HealthStatus) {
+		results <- status
+		if len(results) == 3 {
+			cancel()
+		}
+	})
+	<-ctx.Done()
+	// 结束后不再回调
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(3, len(results))
+	for i := 0; i < 3; i++ {
+		assert.True((<-results).IsHealthy())
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"time"
+)
+
+const (
+	// FromCacheValue the value key of response which is from http cache
+	FromCacheValue = "fromCache"
+
+	// HeaderETag etag
+	HeaderETag = "ETag"
+	// HeaderLastModified last modified
+	HeaderLastModified = "Last-Modified"
+	// HeaderIfNoneMatch if none match
+	HeaderIfNoneMatch = "If-None-Match"
+	// HeaderIfModifiedSince if modified since
+	HeaderIfModifiedSince = "If-Modified-Since"
+	// HeaderCacheControl cache control
+	HeaderCacheControl = "Cache-Control"
+
+	// httpCacheListenerPriority 304 的处理最先执行，其它监听获取的是缓存的响应
+	httpCacheListenerPriority = math.MinInt32
+)
+
+type (
+	// CacheEntry the cached response of http cache
+	CacheEntry struct {
+		// Header the header of response
+		Header http.Header
+		// Body the body of response
+		Body []byte
+		// ETag the etag of response
+		ETag string
+		// LastModified the last modified of response
+		LastModified string
+		// ExpiredAt the response is fresh before it(max-age),
+		// it should be revalidated after expired
+		ExpiredAt time.Time
+	}
+	// CacheStore the store of http cache, the key is the canonical url,
+	// and the sha256 of Authorization and Cookie is appended if they are set
+	CacheStore interface {
+		Get(key string) (entry *CacheEntry, ok bool)
+		Set(key string, entry *CacheEntry)
+	}
+
+	lruCacheStore struct {
+		cache *lru[string, *CacheEntry]
+	}
+)
+
+// NewLRUCacheStore create a memory store of http cache,
+// the least recently used entry will be removed if the entries are
+// more than max entries.
+func NewLRUCacheStore(maxEntries int) CacheStore {
+	return &lruCacheStore{
+		cache: newLRU[string, *CacheEntry](maxEntries),
+	}
+}
+
+func (s *lruCacheStore) Get(key string) (entry *CacheEntry, ok bool) {
+	return s.cache.Get(key)
+}
+
+func (s *lruCacheStore) Set(key string, entry *CacheEntry) {
+	s.cache.Set(key, entry)
+}
+
+// EnableCache enable the http cache of get requests, the fresh response
+// (max-age of Cache-Control) is used without request, and the expired
+// response is revalidated by If-None-Match and If-Modified-Since,
+// the 304 response is replaced by the cached response(200).
+// The response from cache is marked by the value of FromCacheValue,
+// and the private response isn't stored as the cache is shared.
+func (ins *Instance) EnableCache(store CacheStore) *Instance {
+	ins.cacheStore = store
+	return ins
+}
+
+// getHTTPCacheKey get the key of http cache, only get request is cacheable,
+// the response of pipe or stream mode isn't read into body, so it isn't cacheable.
+func (d *Dusk) getHTTPCacheKey() string {
+	if d.cacheStore == nil || d.method != http.MethodGet || d.Request == nil ||
+		d.isPipeMode() || d.isStreamMode() {
+		return ""
+	}
+	key := canonicalURL(d.Request.URL)
+	// 避免不同用户共用缓存，凭证使用 hash，不保存至 store 中
+	if credentials := d.getCacheCredentials(); credentials != "" {
+		sum := sha256.Sum256([]byte(credentials))
+		key += " " + hex.EncodeToString(sum[:])
+	}
+	return key
+}
+
+// useHTTPCacheEntry set the response and body of dusk from http cache entry
+func (d *Dusk) useHTTPCacheEntry(entry *CacheEntry) {
+	d.Response = &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       d.Request,
+	}
+	d.Body = entry.Body
+	d.SetValue(FromCacheValue, true)
+}
+
+// getFromHTTPCache get the fresh response from http cache
+func (d *Dusk) getFromHTTPCache() bool {
+	key := d.getHTTPCacheKey()
+	if key == "" {
+		return false
+	}
+	entry, ok := d.cacheStore.Get(key)
+	if !ok || !d.getClock().Now().Before(entry.ExpiredAt) {
+		return false
+	}
+	d.useHTTPCacheEntry(entry)
+	return true
+}
+
+// setHTTPCache set the store of http cache and add the listeners
+func (d *Dusk) setHTTPCache(store CacheStore) {
+	if d.cacheStore != nil {
+		d.cacheStore = store
+		return
+	}
+	d.cacheStore = store
+	// 添加校验的请求头，最后执行以保证使用的是最终的凭证
+	d.AddRequestListenerWithPriority(func(req *http.Request, d *Dusk) error {
+		key := d.getHTTPCacheKey()
+		if key == "" {
+			return nil
+		}
+		entry, ok := d.cacheStore.Get(key)
+		if !ok {
+			return nil
+		}
+		if entry.ETag != "" && req.Header.Get(HeaderIfNoneMatch) == "" {
+			req.Header.Set(HeaderIfNoneMatch, entry.ETag)
+		}
+		if entry.LastModified != "" && req.Header.Get(HeaderIfModifiedSince) == "" {
+			req.Header.Set(HeaderIfModifiedSince, entry.LastModified)
+		}
+		return nil
+	}, EventTypeBefore, math.MaxInt32)
+	// 304 时使用缓存的数据
+	d.AddResponseListenerWithPriority(func(resp *http.Response, d *Dusk) error {
+		if resp.StatusCode != http.StatusNotModified {
+			return nil
+		}
+		key := d.getHTTPCacheKey()
+		if key == "" {
+			return nil
+		}
+		entry, ok := d.cacheStore.Get(key)
+		if !ok {
+			return nil
+		}
+		header := entry.Header.Clone()
+		// 使用 304 响应中更新的响应头
+		for k, values := range resp.Header {
+			header[k] = values
+		}
+		resp.Status = "200 OK"
+		resp.StatusCode = http.StatusOK
+		resp.Header = header
+		resp.ContentLength = int64(len(entry.Body))
+		d.Body = entry.Body
+		d.SetValue(FromCacheValue, true)
+		// 更新有效期
+		now := d.getClock().Now()
+		ttl, _ := getCacheFreshness(resp.Header, now).ttl(0)
+		d.cacheStore.Set(key, &CacheEntry{
+			Header:       entry.Header,
+			Body:         entry.Body,
+			ETag:         entry.ETag,
+			LastModified: entry.LastModified,
+			ExpiredAt:    now.Add(ttl),
+		})
+		return nil
+	}, EventTypeBefore, httpCacheListenerPriority)
+	// 保存成功的响应
+	d.AddResponseListener(func(resp *http.Response, d *Dusk) error {
+		key := d.getHTTPCacheKey()
+		if key == "" || resp.StatusCode != http.StatusOK || d.GetValue(FromCacheValue) != nil {
+			return nil
+		}
+		now := d.getClock().Now()
+		freshness := getCacheFreshness(resp.Header, now)
+		// instance 的缓存为共享缓存，不保存 private 的响应
+		if freshness.private {
+			return nil
+		}
+		ttl, ok := freshness.ttl(0)
+		etag := resp.Header.Get(HeaderETag)
+		lastModified := resp.Header.Get(HeaderLastModified)
+		// no-store 或无法校验且已过期的不保存
+		if !ok || (ttl == 0 && etag == "" && lastModified == "") {
+			return nil
+		}
+		header := resp.Header.Clone()
+		// 缓存的是解压后的数据
+		header.Del(HeaderContentEncoding)
+		header.Del(HeaderContentLength)
+		d.cacheStore.Set(key, &CacheEntry{
+			Header:       header,
+			Body:         d.Body,
+			ETag:         etag,
+			LastModified: lastModified,
+			ExpiredAt:    now.Add(ttl),
+		})
+		return nil
+	}, EventTypeAfter)
+}
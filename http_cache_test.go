@@ -0,0 +1,207 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCacheStore(t *testing.T) {
+	assert := assert.New(t)
+	store := NewLRUCacheStore(2)
+	store.Set("a", &CacheEntry{
+		ETag: "a",
+	})
+	store.Set("b", &CacheEntry{
+		ETag: "b",
+	})
+	_, ok := store.Get("a")
+	assert.True(ok)
+	// b 最久未使用，被删除
+	store.Set("c", &CacheEntry{
+		ETag: "c",
+	})
+	_, ok = store.Get("b")
+	assert.False(ok)
+	entry, ok := store.Get("a")
+	assert.True(ok)
+	assert.Equal("a", entry.ETag)
+}
+
+func TestHTTPCache(t *testing.T) {
+	var hits, notModified int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		etag := `"` + r.URL.Path + `"`
+		if r.URL.Path == "/private" {
+			w.Header().Set(HeaderCacheControl, "private, max-age=60")
+		}
+		if r.URL.Path == "/max-age" {
+			w.Header().Set(HeaderCacheControl, "max-age=60")
+			// 测试使用的时钟与服务端时间不一致，不返回 Date 避免计算 age
+			w.Header()[HeaderDate] = nil
+		}
+		w.Header().Set(HeaderETag, etag)
+		if r.Header.Get(HeaderIfNoneMatch) == etag {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	}))
+	defer ts.Close()
+	reset := func() {
+		atomic.StoreInt32(&hits, 0)
+		atomic.StoreInt32(&notModified, 0)
+	}
+
+	t.Run("revalidate", func(t *testing.T) {
+		assert := assert.New(t)
+		reset()
+		ins := NewInstance().EnableCache(NewLRUCacheStore(10))
+		d := ins.Get(ts.URL + "/etag")
+		_, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal("GET /etag", string(body))
+		assert.Nil(d.GetValue(FromCacheValue))
+
+		d = ins.Get(ts.URL + "/etag")
+		resp, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal("GET /etag", string(body))
+		assert.Equal(true, d.GetValue(FromCacheValue))
+		assert.Equal(int32(2), atomic.LoadInt32(&hits))
+		assert.Equal(int32(1), atomic.LoadInt32(&notModified))
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		assert := assert.New(t)
+		reset()
+		clock := &stepClock{
+			now: time.Now(),
+		}
+		ins := NewInstance().
+			SetClock(clock).
+			EnableCache(NewLRUCacheStore(10))
+		_, _, err := ins.Get(ts.URL + "/max-age").Do()
+		assert.Nil(err)
+
+		// 未过期，不发送请求
+		d := ins.Get(ts.URL + "/max-age")
+		resp, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal(`"/max-age"`, resp.Header.Get(HeaderETag))
+		assert.Equal("GET /max-age", string(body))
+		assert.Equal(true, d.GetValue(FromCacheValue))
+		assert.Equal(int32(1), atomic.LoadInt32(&hits))
+
+		// 过期后重新校验
+		clock.now = clock.now.Add(61 * time.Second)
+		d = ins.Get(ts.URL + "/max-age")
+		_, body, err = d.Do()
+		assert.Nil(err)
+		assert.Equal("GET /max-age", string(body))
+		assert.Equal(true, d.GetValue(FromCacheValue))
+		assert.Equal(int32(2), atomic.LoadInt32(&hits))
+		assert.Equal(int32(1), atomic.LoadInt32(&notModified))
+
+		// 304 后有效期更新
+		_, _, err = ins.Get(ts.URL + "/max-age").Do()
+		assert.Nil(err)
+		assert.Equal(int32(2), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("bypass non-get", func(t *testing.T) {
+		assert := assert.New(t)
+		reset()
+		ins := NewInstance().EnableCache(NewLRUCacheStore(10))
+		for i := 0; i < 2; i++ {
+			d := ins.Post(ts.URL + "/max-age")
+			_, body, err := d.Do()
+			assert.Nil(err)
+			assert.Equal("POST /max-age", string(body))
+			assert.Nil(d.GetValue(FromCacheValue))
+		}
+		assert.Equal(int32(2), atomic.LoadInt32(&hits))
+		assert.Equal(int32(0), atomic.LoadInt32(&notModified))
+	})
+	t.Run("credentials", func(t *testing.T) {
+		assert := assert.New(t)
+		reset()
+		ins := NewInstance().EnableCache(NewLRUCacheStore(10))
+		for _, token := range []string{"a", "b", "a"} {
+			d := ins.Get(ts.URL+"/max-age").
+				AddRequestListener(func(req *http.Request, _ *Dusk) error {
+					req.Header.Set(HeaderAuthorization, token)
+					return nil
+				}, EventTypeBefore)
+			_, _, err := d.Do()
+			assert.Nil(err)
+		}
+		// 不同的用户不共用缓存
+		assert.Equal(int32(2), atomic.LoadInt32(&hits))
+		assert.Equal(int32(0), atomic.LoadInt32(&notModified))
+	})
+
+	t.Run("not store private", func(t *testing.T) {
+		assert := assert.New(t)
+		reset()
+		ins := NewInstance().EnableCache(NewLRUCacheStore(10))
+		for i := 0; i < 2; i++ {
+			d := ins.Get(ts.URL + "/private")
+			_, body, err := d.Do()
+			assert.Nil(err)
+			assert.Equal("GET /private", string(body))
+			assert.Nil(d.GetValue(FromCacheValue))
+		}
+		assert.Equal(int32(2), atomic.LoadInt32(&hits))
+		assert.Equal(int32(0), atomic.LoadInt32(&notModified))
+	})
+
+	t.Run("bypass pipe and stream", func(t *testing.T) {
+		assert := assert.New(t)
+		reset()
+		ins := NewInstance().EnableCache(NewLRUCacheStore(10))
+		for i := 0; i < 2; i++ {
+			b := new(bytes.Buffer)
+			d := ins.Get(ts.URL + "/etag").Pipe(b)
+			_, _, err := d.Do()
+			assert.Nil(err)
+			assert.Equal("GET /etag", b.String())
+			assert.Nil(d.GetValue(FromCacheValue))
+		}
+		for i := 0; i < 2; i++ {
+			d := ins.Get(ts.URL + "/etag").Stream()
+			_, _, err := d.Do()
+			assert.Nil(err)
+			buf, err := ioutil.ReadAll(d.GetResponseReader())
+			assert.Nil(err)
+			assert.Equal("GET /etag", string(buf))
+			d.Close()
+		}
+		assert.Equal(int32(4), atomic.LoadInt32(&hits))
+		assert.Equal(int32(0), atomic.LoadInt32(&notModified))
+	})
+}
@@ -16,6 +16,9 @@ package dusk
 
 import (
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
 )
 
 type (
@@ -25,7 +28,31 @@ type (
 		responseEvent  []*ResponseEvent
 		errorListeners []ErrorListener
 		doneListeners  []DoneListener
-		config         *Config
+		// errorListenerIDs doneListenerIDs 与 listener 一一对应的 id，用于删除，未指定的为 0
+		errorListenerIDs []ListenerID
+		doneListenerIDs  []ListenerID
+		config           *Config
+		cacheTTL         time.Duration
+		clock            Clock
+		bodyHashStore    BodyHashStore
+		// unchangedListener 如果有设置，则对比响应数据与上次是否相同
+		unchangedListener UnchangedListener
+		circuitBreaker    CircuitBreaker
+		redactedHeaders   []string
+		headerPolicies    []*HeaderPolicy
+		rateLimiter       *rateLimiter
+		cookieJar         http.CookieJar
+		redirectPolicy    RedirectPolicy
+		cacheStore        CacheStore
+		// proxy 所有请求共用的代理，在共用的 transport 中设置
+		proxy func(*http.Request) (*url.URL, error)
+		// proxyErr 代理设置出错，请求时返回
+		proxyErr error
+		// socks5Proxy 所有请求共用的 socks5 代理，在共用的 transport 中设置 dialer
+		socks5Proxy *url.URL
+		// transports 按 instance 的配置调整的 transport，所有请求共用
+		transports    *transportCache
+		transportLock sync.Mutex
 	}
 )
 
@@ -41,36 +68,136 @@ func NewInstanceWithConfig(config Config) *Instance {
 	}
 }
 
+// Clone clone the instance, the listeners and config are copied,
+// so the modification of the clone won't affect the original instance.
+func (ins *Instance) Clone() *Instance {
+	clone := &Instance{
+		cacheTTL:          ins.cacheTTL,
+		clock:             ins.clock,
+		bodyHashStore:     ins.bodyHashStore,
+		unchangedListener: ins.unchangedListener,
+		circuitBreaker:    ins.circuitBreaker,
+		rateLimiter:       ins.rateLimiter,
+		cookieJar:         ins.cookieJar,
+		redirectPolicy:    ins.redirectPolicy,
+		cacheStore:        ins.cacheStore,
+		proxy:             ins.proxy,
+		proxyErr:          ins.proxyErr,
+		socks5Proxy:       ins.socks5Proxy,
+	}
+	if ins.redactedHeaders != nil {
+		clone.redactedHeaders = append([]string{}, ins.redactedHeaders...)
+	}
+	if ins.headerPolicies != nil {
+		clone.headerPolicies = append([]*HeaderPolicy{}, ins.headerPolicies...)
+	}
+	if ins.requestEvents != nil {
+		clone.requestEvents = append([]*RequestEvent{}, ins.requestEvents...)
+	}
+	if ins.responseEvent != nil {
+		clone.responseEvent = append([]*ResponseEvent{}, ins.responseEvent...)
+	}
+	if ins.errorListeners != nil {
+		clone.errorListeners = append([]ErrorListener{}, ins.errorListeners...)
+		clone.errorListenerIDs = append([]ListenerID{}, ins.errorListenerIDs...)
+	}
+	if ins.doneListeners != nil {
+		clone.doneListeners = append([]DoneListener{}, ins.doneListeners...)
+		clone.doneListenerIDs = append([]ListenerID{}, ins.doneListenerIDs...)
+	}
+	if ins.config != nil {
+		config := *ins.config
+		config.Headers = ins.config.Headers.Clone()
+		clone.config = &config
+	}
+	return clone
+}
+
 // AddRequestListener add request listener
 func (ins *Instance) AddRequestListener(ln RequestListener, eventType int) *Instance {
+	return ins.AddRequestListenerWithPriority(ln, eventType, 0)
+}
+
+// AddRequestListenerWithPriority add request listener with priority,
+// the lower priority runs earlier, AddRequestListener is priority 0.
+func (ins *Instance) AddRequestListenerWithPriority(ln RequestListener, eventType, priority int) *Instance {
 	if ins.requestEvents == nil {
 		ins.requestEvents = make([]*RequestEvent, 0)
 	}
 	ins.requestEvents = append(ins.requestEvents, &RequestEvent{
-		ln: ln,
-		t:  eventType,
+		ln:       ln,
+		t:        eventType,
+		priority: priority,
 	})
 	return ins
 }
 
 // AddResponseListener add response listener
 func (ins *Instance) AddResponseListener(ln ResponseListener, eventType int) *Instance {
+	return ins.AddResponseListenerWithPriority(ln, eventType, 0)
+}
+
+// AddResponseListenerWithPriority add response listener with priority,
+// the lower priority runs earlier, AddResponseListener is priority 0.
+func (ins *Instance) AddResponseListenerWithPriority(ln ResponseListener, eventType, priority int) *Instance {
 	if ins.responseEvent == nil {
 		ins.responseEvent = make([]*ResponseEvent, 0)
 	}
 	ins.responseEvent = append(ins.responseEvent, &ResponseEvent{
-		ln: ln,
-		t:  eventType,
+		ln:       ln,
+		t:        eventType,
+		priority: priority,
 	})
 	return ins
 }
 
+// NamedRequestListener add request listener with name,
+// it can be removed by RemoveRequestListener.
+func (ins *Instance) NamedRequestListener(name string, ln RequestListener, eventType int) *Instance {
+	ins.AddRequestListener(ln, eventType)
+	ins.requestEvents[len(ins.requestEvents)-1].name = name
+	return ins
+}
+
+// RemoveRequestListener remove the request listeners of the name
+func (ins *Instance) RemoveRequestListener(name string) *Instance {
+	events := make([]*RequestEvent, 0, len(ins.requestEvents))
+	for _, event := range ins.requestEvents {
+		if event.name != name {
+			events = append(events, event)
+		}
+	}
+	ins.requestEvents = events
+	return ins
+}
+
+// NamedResponseListener add response listener with name,
+// it can be removed by RemoveResponseListener.
+func (ins *Instance) NamedResponseListener(name string, ln ResponseListener, eventType int) *Instance {
+	ins.AddResponseListener(ln, eventType)
+	ins.responseEvent[len(ins.responseEvent)-1].name = name
+	return ins
+}
+
+// RemoveResponseListener remove the response listeners of the name
+func (ins *Instance) RemoveResponseListener(name string) *Instance {
+	events := make([]*ResponseEvent, 0, len(ins.responseEvent))
+	for _, event := range ins.responseEvent {
+		if event.name != name {
+			events = append(events, event)
+		}
+	}
+	ins.responseEvent = events
+	return ins
+}
+
 // AddErrorListener add error listener
 func (ins *Instance) AddErrorListener(ln ErrorListener) *Instance {
 	if ins.errorListeners == nil {
 		ins.errorListeners = make([]ErrorListener, 0)
 	}
 	ins.errorListeners = append(ins.errorListeners, ln)
+	ins.errorListenerIDs = append(ins.errorListenerIDs, 0)
 	return ins
 }
 
@@ -80,6 +207,101 @@ func (ins *Instance) AddDoneListener(ln DoneListener) *Instance {
 		ins.doneListeners = make([]DoneListener, 0)
 	}
 	ins.doneListeners = append(ins.doneListeners, ln)
+	ins.doneListenerIDs = append(ins.doneListenerIDs, 0)
+	return ins
+}
+
+// AddRequestListenerWithID add request listener, the returned id
+// can be used to remove the listener by RemoveListener.
+func (ins *Instance) AddRequestListenerWithID(ln RequestListener, eventType int) ListenerID {
+	ins.AddRequestListener(ln, eventType)
+	id := newListenerID()
+	ins.requestEvents[len(ins.requestEvents)-1].id = id
+	return id
+}
+
+// AddResponseListenerWithID add response listener, the returned id
+// can be used to remove the listener by RemoveListener.
+func (ins *Instance) AddResponseListenerWithID(ln ResponseListener, eventType int) ListenerID {
+	ins.AddResponseListener(ln, eventType)
+	id := newListenerID()
+	ins.responseEvent[len(ins.responseEvent)-1].id = id
+	return id
+}
+
+// AddErrorListenerWithID add error listener, the returned id
+// can be used to remove the listener by RemoveListener.
+func (ins *Instance) AddErrorListenerWithID(ln ErrorListener) ListenerID {
+	ins.AddErrorListener(ln)
+	id := newListenerID()
+	ins.errorListenerIDs[len(ins.errorListenerIDs)-1] = id
+	return id
+}
+
+// AddDoneListenerWithID add done listener, the returned id
+// can be used to remove the listener by RemoveListener.
+func (ins *Instance) AddDoneListenerWithID(ln DoneListener) ListenerID {
+	ins.AddDoneListener(ln)
+	id := newListenerID()
+	ins.doneListenerIDs[len(ins.doneListenerIDs)-1] = id
+	return id
+}
+
+// RemoveListener remove the request, response, error or done listener of id,
+// the requests which are created won't be affected.
+func (ins *Instance) RemoveListener(id ListenerID) *Instance {
+	requestEvents := make([]*RequestEvent, 0, len(ins.requestEvents))
+	for _, e := range ins.requestEvents {
+		if e.id != id {
+			requestEvents = append(requestEvents, e)
+		}
+	}
+	ins.requestEvents = requestEvents
+	responseEvents := make([]*ResponseEvent, 0, len(ins.responseEvent))
+	for _, e := range ins.responseEvent {
+		if e.id != id {
+			responseEvents = append(responseEvents, e)
+		}
+	}
+	ins.responseEvent = responseEvents
+	ins.errorListeners, ins.errorListenerIDs = removeListenerOfID(ins.errorListeners, ins.errorListenerIDs, id)
+	ins.doneListeners, ins.doneListenerIDs = removeListenerOfID(ins.doneListeners, ins.doneListenerIDs, id)
+	return ins
+}
+
+// StrictResponseHeaders reject the response which has conflicting
+// duplicate values of Content-Length, Content-Type or Location
+func (ins *Instance) StrictResponseHeaders() *Instance {
+	return ins.AddResponseListener(CheckResponseHeaders, EventTypeBefore)
+}
+
+// ExpireAfter cache the response of get request for ttl
+func (ins *Instance) ExpireAfter(ttl time.Duration) *Instance {
+	ins.cacheTTL = ttl
+	return ins
+}
+
+// SetClock set the clock for all requests of instance
+func (ins *Instance) SetClock(c Clock) *Instance {
+	ins.clock = c
+	return ins
+}
+
+// SetBodyHashStore set the body hash store for unchanged detector
+func (ins *Instance) SetBodyHashStore(store BodyHashStore) *Instance {
+	ins.bodyHashStore = store
+	return ins
+}
+
+// OnUnchanged set the listener of unchanged response, the hash of body
+// will be compared with the previous one of the same method and canonical url,
+// the listener will be called if they are the same.
+// The memory store of 1024 entries is used if the store is not set.
+func (ins *Instance) OnUnchanged(fn UnchangedListener) *Instance {
+	ins.unchangedListener = fn
+	if ins.bodyHashStore == nil {
+		ins.bodyHashStore = NewMemoryBodyHashStore(defaultBodyHashStoreSize)
+	}
 	return ins
 }
 
@@ -96,6 +318,45 @@ func (ins *Instance) init(d *Dusk) {
 	if ins.doneListeners != nil {
 		d.AddDoneListener(ins.doneListeners...)
 	}
+	if ins.clock != nil {
+		d.SetClock(ins.clock)
+	}
+	if ins.cacheTTL != 0 {
+		d.ExpireAfter(ins.cacheTTL)
+	}
+	if ins.circuitBreaker != nil {
+		addCircuitBreaker(d, ins.circuitBreaker)
+	}
+	for _, header := range ins.redactedHeaders {
+		d.RedactHeader(header)
+	}
+	if ins.rateLimiter != nil {
+		d.setRateLimiter(ins.rateLimiter)
+	}
+	if ins.cookieJar != nil {
+		d.WithCookieJar(ins.cookieJar)
+	}
+	if ins.redirectPolicy != nil {
+		d.SetRedirectPolicy(ins.redirectPolicy)
+	}
+	if ins.cacheStore != nil {
+		d.setHTTPCache(ins.cacheStore)
+	}
+	if len(ins.headerPolicies) != 0 {
+		d.headerPolicies = append(d.headerPolicies, ins.headerPolicies...)
+	}
+	if ins.unchangedListener != nil {
+		d.AddResponseListener(newUnchangedDetector(ins.bodyHashStore, ins.unchangedListener), EventTypeAfter)
+	}
+	tc, err := ins.getTransportCache()
+	if err == nil {
+		err = ins.proxyErr
+	}
+	if err != nil {
+		d.buildErr = err
+	} else if tc != nil {
+		d.transportCache = tc
+	}
 	cfg := ins.config
 	if cfg != nil {
 		if len(cfg.Headers) != 0 {
@@ -111,6 +372,72 @@ func (ins *Instance) init(d *Dusk) {
 	}
 }
 
+// getTransportCache get the transport cache of instance, it will be created
+// at the first time and shared by all requests, nil will be returned
+// if there is no transport setting of instance.
+func (ins *Instance) getTransportCache() (*transportCache, error) {
+	ins.transportLock.Lock()
+	defer ins.transportLock.Unlock()
+	if ins.transports != nil {
+		return ins.transports, nil
+	}
+	tc := &transportCache{}
+	if ins.proxy != nil {
+		proxy := ins.proxy
+		tc.setters = append(tc.setters, func(t *http.Transport) error {
+			t.Proxy = proxy
+			return nil
+		})
+	}
+	cfg := ins.config
+	if cfg != nil && cfg.LocalAddr != "" {
+		addr, err := parseLocalAddr(cfg.LocalAddr)
+		if err != nil {
+			return nil, err
+		}
+		tc.dialer = newDialer()
+		tc.dialer.LocalAddr = addr
+	}
+	if ins.socks5Proxy != nil {
+		if tc.dialer == nil {
+			tc.dialer = newDialer()
+		}
+		tc.socks5 = ins.socks5Proxy
+		dialContext, err := newSOCKS5DialContext(tc.socks5, tc.dialer)
+		if err != nil {
+			return nil, err
+		}
+		tc.setters = append(tc.setters, func(t *http.Transport) error {
+			// 连接由 socks5 代理建立，不再使用 http 代理
+			t.Proxy = nil
+			t.DialContext = dialContext
+			return nil
+		})
+	} else if tc.dialer != nil {
+		dialContext := newDialContext(tc.dialer)
+		tc.setters = append(tc.setters, func(t *http.Transport) error {
+			t.DialContext = dialContext
+			return nil
+		})
+	}
+	if len(tc.setters) == 0 {
+		return nil, nil
+	}
+	ins.transports = tc
+	return tc, nil
+}
+
+// resetTransportCache reset the transport cache after the transport
+// settings are changed, the idle connections of old transport are closed.
+func (ins *Instance) resetTransportCache() {
+	ins.transportLock.Lock()
+	defer ins.transportLock.Unlock()
+	if ins.transports != nil {
+		ins.transports.closeIdleConnections()
+		ins.transports = nil
+	}
+}
+
 // Get http get request
 func (ins *Instance) Get(url string) *Dusk {
 	url = prependURL(url, ins.config)
@@ -162,5 +489,6 @@ func (ins *Instance) Delete(url string) *Dusk {
 // SetConfig set config for instance
 func (ins *Instance) SetConfig(config Config) *Instance {
 	ins.config = &config
+	ins.resetTransportCache()
 	return ins
 }
@@ -152,3 +152,178 @@ func TestInstanceSetConfig(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(resp.StatusCode, 204)
 }
+
+func TestInstanceStrictResponseHeaders(t *testing.T) {
+	assert := assert.New(t)
+	defer gock.Off()
+	gock.New("http://aslant.site").
+		Get("/").
+		Reply(302).
+		AddHeader(HeaderLocation, "/a").
+		AddHeader(HeaderLocation, "/b")
+	ins := NewInstance().StrictResponseHeaders()
+	_, _, err := ins.Get("http://aslant.site/").DisableRedirect().Do()
+	me, ok := err.(*MalformedResponseError)
+	assert.True(ok)
+	assert.Equal(HeaderLocation, me.Header)
+}
+
+func TestInstanceClone(t *testing.T) {
+	assert := assert.New(t)
+	ins := NewInstanceWithConfig(Config{
+		BaseURL: "https://aslant.site",
+		Headers: http.Header{
+			"X-Token": []string{"abc"},
+		},
+	})
+	ins.AddRequestListener(func(_ *http.Request, _ *Dusk) error {
+		return nil
+	}, EventTypeBefore)
+	ins.AddDoneListener(func(_ *Dusk) error {
+		return nil
+	})
+
+	clone := ins.Clone()
+	clone.AddRequestListener(func(_ *http.Request, _ *Dusk) error {
+		return nil
+	}, EventTypeAfter)
+	clone.AddResponseListener(func(_ *http.Response, _ *Dusk) error {
+		return nil
+	}, EventTypeBefore)
+	clone.AddErrorListener(func(err error, _ *Dusk) error {
+		return err
+	})
+	clone.AddDoneListener(func(_ *Dusk) error {
+		return nil
+	})
+	clone.config.BaseURL = "https://tiny.aslant.site"
+	clone.config.Headers.Set("X-Token", "def")
+
+	assert.Equal(1, len(ins.requestEvents))
+	assert.Equal(0, len(ins.responseEvent))
+	assert.Equal(0, len(ins.errorListeners))
+	assert.Equal(1, len(ins.doneListeners))
+	assert.Equal("https://aslant.site", ins.config.BaseURL)
+	assert.Equal("abc", ins.config.Headers.Get("X-Token"))
+
+	assert.Equal(2, len(clone.requestEvents))
+	assert.Equal(1, len(clone.responseEvent))
+	assert.Equal(1, len(clone.errorListeners))
+	assert.Equal(2, len(clone.doneListeners))
+	assert.Equal("https://tiny.aslant.site/users", clone.Get("/users").GetURL())
+	assert.Equal("https://aslant.site/users", ins.Get("/users").GetURL())
+}
+
+func TestInstanceNamedListener(t *testing.T) {
+	assert := assert.New(t)
+	defer gock.Off()
+	gock.New("http://aslant.site").
+		Get("/").
+		Times(2).
+		Reply(200).
+		BodyString("done")
+
+	calls := make([]string, 0)
+	ins := NewInstance().
+		NamedRequestListener("logger", func(_ *http.Request, _ *Dusk) error {
+			calls = append(calls, "request logger")
+			return nil
+		}, EventTypeBefore).
+		AddRequestListener(func(_ *http.Request, _ *Dusk) error {
+			calls = append(calls, "request")
+			return nil
+		}, EventTypeBefore).
+		NamedResponseListener("logger", func(_ *http.Response, _ *Dusk) error {
+			calls = append(calls, "response logger")
+			return nil
+		}, EventTypeAfter)
+
+	_, _, err := ins.Get("http://aslant.site/").Do()
+	assert.Nil(err)
+	assert.Equal([]string{
+		"request",
+		"request logger",
+		"response logger",
+	}, calls)
+
+	clone := ins.Clone()
+	ins.RemoveRequestListener("logger").
+		RemoveResponseListener("logger")
+	assert.Equal(1, len(ins.requestEvents))
+	assert.Equal(0, len(ins.responseEvent))
+	// clone 的监听不受影响
+	assert.Equal(2, len(clone.requestEvents))
+	assert.Equal(1, len(clone.responseEvent))
+
+	calls = calls[:0]
+	_, _, err = ins.Get("http://aslant.site/").Do()
+	assert.Nil(err)
+	assert.Equal([]string{
+		"request",
+	}, calls)
+}
+
+func TestInstanceListenerID(t *testing.T) {
+	assert := assert.New(t)
+	defer gock.Off()
+	gock.New("http://aslant.site").
+		Get("/").
+		Times(2).
+		Reply(200).
+		BodyString("done")
+
+	calls := make([]string, 0)
+	ins := NewInstance()
+	reqID := ins.AddRequestListenerWithID(func(_ *http.Request, _ *Dusk) error {
+		calls = append(calls, "request")
+		return nil
+	}, EventTypeBefore)
+	respID := ins.AddResponseListenerWithID(func(_ *http.Response, _ *Dusk) error {
+		calls = append(calls, "response")
+		return nil
+	}, EventTypeAfter)
+	errID := ins.AddErrorListenerWithID(func(err error, _ *Dusk) error {
+		return err
+	})
+	doneID := ins.AddDoneListenerWithID(func(_ *Dusk) error {
+		calls = append(calls, "done")
+		return nil
+	})
+	ins.AddDoneListener(func(_ *Dusk) error {
+		calls = append(calls, "keep")
+		return nil
+	})
+	assert.NotEqual(reqID, respID)
+	assert.NotEqual(errID, doneID)
+
+	// 已创建的请求不受删除影响
+	created := ins.Get("http://aslant.site/")
+	clone := ins.Clone()
+	ins.RemoveListener(reqID).
+		RemoveListener(respID).
+		RemoveListener(errID).
+		RemoveListener(doneID)
+	assert.Equal(0, len(ins.requestEvents))
+	assert.Equal(0, len(ins.responseEvent))
+	assert.Equal(0, len(ins.errorListeners))
+	assert.Equal(1, len(ins.doneListeners))
+	// clone 的监听不受影响
+	assert.Equal(1, len(clone.requestEvents))
+	assert.Equal(2, len(clone.doneListeners))
+
+	_, _, err := ins.Get("http://aslant.site/").Do()
+	assert.Nil(err)
+	assert.Equal([]string{
+		"keep",
+	}, calls)
+
+	calls = calls[:0]
+	_, _, err = created.Do()
+	assert.Nil(err)
+	assert.Equal([]string{
+		"request",
+		"response",
+		"keep",
+		"done",
+	}, calls)
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+type (
+	// jsonStream the data which is encoded as json on the fly
+	jsonStream struct {
+		v interface{}
+	}
+	jsonStreamReader struct {
+		v    interface{}
+		once sync.Once
+		pr   *io.PipeReader
+		pw   *io.PipeWriter
+	}
+)
+
+// SendJSONStream set the data which will be encoded as json while
+// it's sent, the data isn't marshaled into memory first, so it can
+// reduce the peak memory of large body. The encode error will be
+// returned by Do. The request can't be retried or redirected with body
+// because the body can't be read again.
+func (d *Dusk) SendJSONStream(v interface{}) *Dusk {
+	d.data = &jsonStream{
+		v: v,
+	}
+	d.sendContentType = ""
+	return d
+}
+
+// newReader create the reader which encodes the data by json encoder
+func (js *jsonStream) newReader() io.Reader {
+	pr, pw := io.Pipe()
+	return &jsonStreamReader{
+		v:  js.v,
+		pr: pr,
+		pw: pw,
+	}
+}
+
+// Read read the encoded data, the encoder is started on the first read,
+// so no goroutine is left if the request is aborted before sending
+func (r *jsonStreamReader) Read(p []byte) (int, error) {
+	r.once.Do(func() {
+		go func() {
+			// 出错时 reader 读取返回该出错，如果 reader 已关闭，则写入失败退出
+			r.pw.CloseWithError(json.NewEncoder(r.pw).Encode(r.v))
+		}()
+	})
+	return r.pr.Read(p)
+}
+
+// Close close the reader, the encoder will be stopped
+func (r *jsonStreamReader) Close() error {
+	return r.pr.Close()
+}
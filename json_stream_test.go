@@ -0,0 +1,147 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type jsonStreamItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newJSONStreamItems(count int) []jsonStreamItem {
+	items := make([]jsonStreamItem, count)
+	for i := range items {
+		items[i] = jsonStreamItem{
+			ID:   i,
+			Name: strings.Repeat("x", 64),
+		}
+	}
+	return items
+}
+
+func TestSendJSONStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		items := make([]jsonStreamItem, 0)
+		err := json.NewDecoder(r.Body).Decode(&items)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set(HeaderContentType, r.Header.Get(HeaderContentType))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"count":         len(items),
+			"contentLength": r.ContentLength,
+		})
+	}))
+	defer ts.Close()
+
+	t.Run("send", func(t *testing.T) {
+		assert := assert.New(t)
+		_, body, err := Post(ts.URL).
+			SendJSONStream(newJSONStreamItems(100)).
+			Do()
+		assert.Nil(err)
+		// 流式发送无法获取长度
+		assert.Equal(`{"contentLength":-1,"count":100}`, strings.TrimSpace(string(body)))
+	})
+
+	t.Run("content type", func(t *testing.T) {
+		assert := assert.New(t)
+		resp, _, err := Post(ts.URL).
+			SendJSONStream(newJSONStreamItems(1)).
+			Do()
+		assert.Nil(err)
+		assert.Equal(MIMEApplicationJSON, resp.Header.Get(HeaderContentType))
+	})
+
+	t.Run("encode error", func(t *testing.T) {
+		assert := assert.New(t)
+		_, _, err := Post(ts.URL).
+			SendJSONStream(map[string]interface{}{
+				"ch": make(chan int),
+			}).
+			Do()
+		assert.NotNil(err)
+		var typeErr *json.UnsupportedTypeError
+		assert.True(errors.As(err, &typeErr))
+	})
+
+	t.Run("curl", func(t *testing.T) {
+		assert := assert.New(t)
+		cmd := Post(ts.URL).
+			SendJSONStream(map[string]string{
+				"name": "tree.xie",
+			}).
+			ToCurl()
+		assert.Contains(cmd, `{"name":"tree.xie"}`)
+	})
+}
+
+func TestJSONStreamReaderClose(t *testing.T) {
+	assert := assert.New(t)
+	js := &jsonStream{
+		v: newJSONStreamItems(1000),
+	}
+	r := js.newReader().(io.ReadCloser)
+	buf := make([]byte, 10)
+	n, err := r.Read(buf)
+	assert.Nil(err)
+	assert.Equal(10, n)
+	assert.Nil(r.Close())
+	_, err = r.Read(buf)
+	assert.Equal(io.ErrClosedPipe, err)
+}
+
+func benchmarkSendJSON(b *testing.B, stream bool) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+	}))
+	defer ts.Close()
+	items := newJSONStreamItems(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d := Post(ts.URL)
+		if stream {
+			d.SendJSONStream(items)
+		} else {
+			d.Send(items)
+		}
+		_, _, err := d.Do()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSendJSON(b *testing.B) {
+	benchmarkSendJSON(b, false)
+}
+
+func BenchmarkSendJSONStream(b *testing.B) {
+	benchmarkSendJSON(b, true)
+}
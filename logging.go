@@ -0,0 +1,156 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"context"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	loggingStartedAtKey   = "logging.startedAt"
+	loggingRequestBodyKey = "logging.requestBody"
+)
+
+type (
+	// LogOptions the options of logging listeners
+	LogOptions struct {
+		// MaxBodyLog the max size of request and response body to log,
+		// the body won't be logged if it's 0
+		MaxBodyLog int
+		// LogHeaders log the request and response headers
+		LogHeaders bool
+		// RedactHeaders the headers to redact, DefaultSensitiveHeaders
+		// and Set-Cookie are used if it's empty, the headers added by
+		// RedactHeader of request are always redacted
+		RedactHeaders []string
+		// DisableRedact log the headers without redacting
+		DisableRedact bool
+	}
+	// LoggingListeners the listeners log the requests by slog
+	LoggingListeners struct {
+		logger *slog.Logger
+		opts   LogOptions
+	}
+)
+
+// NewLoggingListeners create the listeners logging method, final url, status,
+// elapsed time and the truncated bodies(if MaxBodyLog is set) of requests,
+// the failed request is logged at error level with the error.
+func NewLoggingListeners(logger *slog.Logger, opts LogOptions) *LoggingListeners {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if len(opts.RedactHeaders) == 0 {
+		opts.RedactHeaders = append([]string{"Set-Cookie"}, DefaultSensitiveHeaders...)
+	}
+	return &LoggingListeners{
+		logger: logger,
+		opts:   opts,
+	}
+}
+
+// Attach add the listeners to instance,
+// so all requests created from the instance will be logged
+func (l *LoggingListeners) Attach(ins *Instance) *Instance {
+	return ins.AddRequestListener(l.OnRequest, EventTypeBefore).
+		AddDoneListener(l.OnDone)
+}
+
+// OnRequest the request listener recording the start time and request body,
+// it should be added as before event
+func (l *LoggingListeners) OnRequest(req *http.Request, d *Dusk) error {
+	d.SetValue(loggingStartedAtKey, d.getClock().Now())
+	// 只记录可重复读取的 body，避免影响请求数据
+	if l.opts.MaxBodyLog > 0 && req.GetBody != nil {
+		r, err := req.GetBody()
+		if err == nil {
+			buf, _ := ioutil.ReadAll(r)
+			r.Close()
+			d.SetValue(loggingRequestBodyKey, l.truncate(buf))
+		}
+	}
+	return nil
+}
+
+// OnDone the done listener logging the request
+func (l *LoggingListeners) OnDone(d *Dusk) error {
+	attrs := []slog.Attr{
+		slog.String("method", d.GetMethod()),
+		slog.String("url", getFinalURL(d)),
+	}
+	if d.Response != nil {
+		attrs = append(attrs, slog.Int("status", d.Response.StatusCode))
+	}
+	if startedAt, ok := d.GetValue(loggingStartedAtKey).(time.Time); ok {
+		attrs = append(attrs, slog.Duration("elapsed", d.getClock().Now().Sub(startedAt)))
+	}
+	if l.opts.LogHeaders {
+		if d.Request != nil {
+			attrs = append(attrs, slog.Any("requestHeader", l.redact(d, d.Request.Header)))
+		}
+		if d.Response != nil {
+			attrs = append(attrs, slog.Any("responseHeader", l.redact(d, d.Response.Header)))
+		}
+	}
+	if l.opts.MaxBodyLog > 0 {
+		if body, ok := d.GetValue(loggingRequestBodyKey).(string); ok {
+			attrs = append(attrs, slog.String("requestBody", body))
+		}
+		if d.Body != nil {
+			attrs = append(attrs, slog.String("responseBody", l.truncate(d.Body)))
+		}
+	}
+	ctx := d.GetContext()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if d.Err != nil {
+		attrs = append(attrs, slog.Any("error", d.Err))
+		l.logger.LogAttrs(ctx, slog.LevelError, "http request fail", attrs...)
+		return nil
+	}
+	l.logger.LogAttrs(ctx, slog.LevelInfo, "http request", attrs...)
+	return nil
+}
+
+func (l *LoggingListeners) truncate(buf []byte) string {
+	if len(buf) > l.opts.MaxBodyLog {
+		return string(buf[:l.opts.MaxBodyLog]) + "..."
+	}
+	return string(buf)
+}
+
+func (l *LoggingListeners) redact(d *Dusk, header http.Header) http.Header {
+	if l.opts.DisableRedact {
+		return header
+	}
+	keys := append(append([]string{}, l.opts.RedactHeaders...), d.GetRedactedHeaders()...)
+	return redactHeader(header, keys...)
+}
+
+// getFinalURL get the url of request after redirects
+func getFinalURL(d *Dusk) string {
+	if d.Response != nil && d.Response.Request != nil {
+		return d.Response.Request.URL.String()
+	}
+	if d.Request != nil {
+		return d.Request.URL.String()
+	}
+	return d.GetURL()
+}
@@ -0,0 +1,150 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggingListeners(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/users", http.StatusFound)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal error"))
+		default:
+			http.SetCookie(w, &http.Cookie{
+				Name:  "jt",
+				Value: "abc",
+			})
+			w.Write([]byte(`{"name":"tree.xie","type":"vip"}`))
+		}
+	}))
+	defer ts.Close()
+
+	newLogger := func(b *bytes.Buffer) *slog.Logger {
+		return slog.New(slog.NewTextHandler(b, nil))
+	}
+
+	t.Run("log request", func(t *testing.T) {
+		assert := assert.New(t)
+		b := new(bytes.Buffer)
+		ins := NewLoggingListeners(newLogger(b), LogOptions{
+			MaxBodyLog: 10,
+			LogHeaders: true,
+		}).Attach(NewInstance())
+		_, _, err := ins.Post(ts.URL+"/redirect").
+			Set("Authorization", "Bearer token").
+			Send(map[string]string{
+				"account": "tree.xie",
+			}).
+			Do()
+		assert.Nil(err)
+		log := b.String()
+		assert.True(strings.HasPrefix(log, "time="))
+		assert.Contains(log, "level=INFO")
+		assert.Contains(log, "method=POST")
+		assert.Contains(log, "url="+ts.URL+"/users")
+		assert.Contains(log, "status=200")
+		assert.Contains(log, "elapsed=")
+		assert.Contains(log, `requestBody="{\"account\"..."`)
+		assert.Contains(log, `responseBody="{\"name\":\"t..."`)
+		assert.Contains(log, "Authorization:[***]")
+		assert.Contains(log, "Set-Cookie:[***]")
+		assert.NotContains(log, "Bearer token")
+		assert.NotContains(log, "jt=abc")
+	})
+
+	t.Run("elapsed of clock", func(t *testing.T) {
+		assert := assert.New(t)
+		b := new(bytes.Buffer)
+		clock := &stepClock{
+			now: time.Now(),
+		}
+		ins := NewLoggingListeners(newLogger(b), LogOptions{}).
+			Attach(NewInstance().SetClock(clock)).
+			AddResponseListener(func(_ *http.Response, _ *Dusk) error {
+				clock.now = clock.now.Add(time.Second)
+				return nil
+			}, EventTypeAfter)
+		_, _, err := ins.Get(ts.URL).Do()
+		assert.Nil(err)
+		assert.Contains(b.String(), "elapsed=1s")
+	})
+
+	t.Run("redact header", func(t *testing.T) {
+		assert := assert.New(t)
+		b := new(bytes.Buffer)
+		ins := NewInstance().RedactHeader("X-Secret")
+		NewLoggingListeners(newLogger(b), LogOptions{
+			LogHeaders: true,
+		}).Attach(ins)
+		_, _, err := ins.Get(ts.URL).
+			Set("X-Secret", "secret").
+			Set("X-API-Key", "key").
+			RedactHeader("X-Session").
+			Set("X-Session", "session").
+			Do()
+		assert.Nil(err)
+		log := b.String()
+		assert.Contains(log, "X-Secret:[***]")
+		assert.Contains(log, "X-Api-Key:[***]")
+		assert.Contains(log, "X-Session:[***]")
+		assert.NotContains(log, "secret]")
+	})
+
+	t.Run("disable redact", func(t *testing.T) {
+		assert := assert.New(t)
+		b := new(bytes.Buffer)
+		ins := NewLoggingListeners(newLogger(b), LogOptions{
+			LogHeaders:    true,
+			DisableRedact: true,
+		}).Attach(NewInstance())
+		_, _, err := ins.Get(ts.URL).
+			Set("Authorization", "Bearer token").
+			Do()
+		assert.Nil(err)
+		log := b.String()
+		assert.Contains(log, "Bearer token")
+		assert.NotContains(log, "responseBody")
+	})
+
+	t.Run("log error", func(t *testing.T) {
+		assert := assert.New(t)
+		b := new(bytes.Buffer)
+		ins := NewLoggingListeners(newLogger(b), LogOptions{}).
+			Attach(NewInstance())
+		_, _, err := ins.Get(ts.URL + "/error").
+			Expect2xx().
+			Do()
+		re := &ResponseError{}
+		assert.True(errors.As(err, &re))
+		log := b.String()
+		assert.Contains(log, "level=ERROR")
+		assert.Contains(log, "status=500")
+		assert.Contains(log, `error="GET `+ts.URL+`/error: unexpected status code 500"`)
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"container/list"
+	"sync"
+)
+
+type (
+	// lru the least recently used cache which is safe for concurrent use,
+	// the least recently used entry will be removed if the entries are
+	// more than max entries, zero means unlimited.
+	lru[K comparable, V any] struct {
+		sync.Mutex
+		maxEntries int
+		ll         *list.List
+		items      map[K]*list.Element
+	}
+	lruItem[K comparable, V any] struct {
+		key   K
+		value V
+	}
+)
+
+func newLRU[K comparable, V any](maxEntries int) *lru[K, V] {
+	return &lru[K, V]{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[K]*list.Element),
+	}
+}
+
+// Get get the value of key and mark it as recently used
+func (c *lru[K, V]) Get(key K) (value V, ok bool) {
+	c.Lock()
+	defer c.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*lruItem[K, V]).value, true
+}
+
+// Set set the value of key and mark it as recently used
+func (c *lru[K, V]) Set(key K, value V) {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*lruItem[K, V]).value = value
+		return
+	}
+	c.items[key] = c.ll.PushFront(&lruItem[K, V]{
+		key:   key,
+		value: value,
+	})
+	c.prune()
+}
+
+// Delete delete the value of key
+func (c *lru[K, V]) Delete(key K) {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+// Len get the count of entries
+func (c *lru[K, V]) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.ll.Len()
+}
+
+// Clear remove all entries
+func (c *lru[K, V]) Clear() {
+	c.Lock()
+	defer c.Unlock()
+	c.ll.Init()
+	c.items = make(map[K]*list.Element)
+}
+
+// SetMaxEntries set the max entries, the least recently used entries
+// which are more than max entries are removed.
+func (c *lru[K, V]) SetMaxEntries(maxEntries int) {
+	c.Lock()
+	defer c.Unlock()
+	c.maxEntries = maxEntries
+	c.prune()
+}
+
+// prune remove the least recently used entries which are more than max entries
+func (c *lru[K, V]) prune() {
+	for c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*lruItem[K, V]).key)
+	}
+}
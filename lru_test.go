@@ -0,0 +1,53 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	assert := assert.New(t)
+	c := newLRU[string, int](2)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	v, ok := c.Get("a")
+	assert.True(ok)
+	assert.Equal(1, v)
+	// b 最久未使用，被删除
+	c.Set("c", 3)
+	_, ok = c.Get("b")
+	assert.False(ok)
+	assert.Equal(2, c.Len())
+
+	c.Set("a", 4)
+	v, _ = c.Get("a")
+	assert.Equal(4, v)
+
+	c.Delete("a")
+	_, ok = c.Get("a")
+	assert.False(ok)
+
+	c.Set("a", 1)
+	c.SetMaxEntries(1)
+	assert.Equal(1, c.Len())
+	_, ok = c.Get("a")
+	assert.True(ok)
+
+	c.Clear()
+	assert.Equal(0, c.Len())
+}
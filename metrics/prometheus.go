@@ -0,0 +1,151 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics provides the listeners recording prometheus metrics of dusk requests
+package metrics
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vicanso/dusk"
+)
+
+const (
+	startedAtKey = "metrics.startedAt"
+	// statusNone the status class of request without response
+	statusNone = "none"
+)
+
+type (
+	// Listeners the listeners recording request duration, errors and retries
+	Listeners struct {
+		duration *prometheus.HistogramVec
+		errors   *prometheus.CounterVec
+		retries  *prometheus.CounterVec
+	}
+)
+
+// NewPrometheusListeners create the listeners of prometheus metrics,
+// the duration histogram is labeled by method, host, status class(2xx, 5xx or none)
+// and the label of request(see dusk.Label, it's the path template by default),
+// the errors and retries counters are labeled by method and host.
+// The collectors should be registered by Register or MustRegister.
+func NewPrometheusListeners(namespace string) *Listeners {
+	return &Listeners{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "request_duration_seconds",
+			Help:      "The duration of http request.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "host", "status", "label"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "errors_total",
+			Help:      "The count of failed http request.",
+		}, []string{"method", "host"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "retries_total",
+			Help:      "The count of retried http request.",
+		}, []string{"method", "host"}),
+	}
+}
+
+// Collectors get the collectors of listeners
+func (l *Listeners) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		l.duration,
+		l.errors,
+		l.retries,
+	}
+}
+
+// Register register the collectors to the registerer
+func (l *Listeners) Register(r prometheus.Registerer) error {
+	for _, c := range l.Collectors() {
+		err := r.Register(c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MustRegister register the collectors to the registerer, it panics if error occurs
+func (l *Listeners) MustRegister(r prometheus.Registerer) {
+	r.MustRegister(l.Collectors()...)
+}
+
+// OnRequest the request listener recording the start time,
+// it should be added as before event
+func (l *Listeners) OnRequest(_ *http.Request, d *dusk.Dusk) error {
+	d.SetValue(startedAtKey, d.GetClock().Now())
+	return nil
+}
+
+// OnDone the done listener recording the metrics of request,
+// the prefetch requests are skipped
+func (l *Listeners) OnDone(d *dusk.Dusk) error {
+	// 预取的请求不统计
+	if d.IsPrefetch() {
+		return nil
+	}
+	method := d.GetMethod()
+	host := getHost(d)
+
+	status := statusNone
+	if d.Response != nil {
+		status = strconv.Itoa(d.Response.StatusCode/100) + "xx"
+	}
+	// 如果启用了 trace，则使用 trace 的时间，否则使用开始请求的时间
+	var duration time.Duration
+	if stats := d.GetTimelineStats(); stats != nil {
+		duration = stats.Total
+	} else if startedAt, ok := d.GetValue(startedAtKey).(time.Time); ok {
+		duration = d.GetClock().Now().Sub(startedAt)
+	}
+	l.duration.WithLabelValues(method, host, status, d.GetLabel()).Observe(duration.Seconds())
+	if d.Err != nil {
+		l.errors.WithLabelValues(method, host).Inc()
+	}
+	if retries := d.GetRetries(); retries != 0 {
+		l.retries.WithLabelValues(method, host).Add(float64(retries))
+	}
+	return nil
+}
+
+// Attach add the listeners to instance,
+// so all requests created from the instance will be recorded
+func (l *Listeners) Attach(ins *dusk.Instance) *dusk.Instance {
+	return ins.AddRequestListener(l.OnRequest, dusk.EventTypeBefore).
+		AddDoneListener(l.OnDone)
+}
+
+func getHost(d *dusk.Dusk) string {
+	if d.Request != nil && d.Request.URL != nil {
+		return d.Request.URL.Host
+	}
+	info, err := url.Parse(d.GetURL())
+	if err != nil {
+		return ""
+	}
+	return info.Host
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/vicanso/dusk"
+)
+
+func TestPrometheusListeners(t *testing.T) {
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	reg := prometheus.NewRegistry()
+	l := NewPrometheusListeners("test")
+	assert.Nil(l.Register(reg))
+
+	ins := l.Attach(dusk.NewInstance())
+	_, _, err := ins.Get(ts.URL).Do()
+	assert.Nil(err)
+	_, _, err = ins.Get(ts.URL).EnableTrace().Do()
+	assert.Nil(err)
+	_, _, err = ins.Get(ts.URL + "/error").Expect2xx().Do()
+	assert.NotNil(err)
+
+	// 不同 id 的请求使用相同的 label
+	for _, id := range []string{"1", "2", "3"} {
+		_, _, err = ins.Get(ts.URL+"/users/:id").Param("id", id).Do()
+		assert.Nil(err)
+	}
+
+	assert.Equal(3, testutil.CollectAndCount(l.duration))
+	assert.Equal(float64(1), testutil.ToFloat64(l.errors.WithLabelValues(http.MethodGet, host)))
+
+	count, err := testutil.GatherAndCount(reg, "test_http_client_request_duration_seconds")
+	assert.Nil(err)
+	assert.Equal(3, count)
+}
+
+func TestPrometheusListenersPrefetch(t *testing.T) {
+	assert := assert.New(t)
+	defer dusk.ClearRequestCache()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	l := NewPrometheusListeners("prefetch")
+	ins := l.Attach(dusk.NewInstance()).ExpireAfter(time.Minute)
+	ins.Prefetch([]string{
+		ts.URL,
+	}, dusk.PrefetchOptions{}).Wait()
+	// 预取的请求不统计
+	assert.Equal(0, testutil.CollectAndCount(l.duration))
+}
+
+type stepClock struct {
+	dusk.Clock
+	now time.Time
+}
+
+func (c *stepClock) Now() time.Time {
+	return c.now
+}
+
+func TestPrometheusListenersClock(t *testing.T) {
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	reg := prometheus.NewRegistry()
+	l := NewPrometheusListeners("clock")
+	assert.Nil(l.Register(reg))
+	clock := &stepClock{
+		Clock: dusk.GetClock(),
+		now:   time.Now(),
+	}
+	ins := l.Attach(dusk.NewInstance().SetClock(clock)).
+		AddResponseListener(func(_ *http.Response, _ *dusk.Dusk) error {
+			clock.now = clock.now.Add(2 * time.Second)
+			return nil
+		}, dusk.EventTypeAfter)
+	_, _, err := ins.Get(ts.URL).Do()
+	assert.Nil(err)
+
+	families, err := reg.Gather()
+	assert.Nil(err)
+	var sum float64
+	for _, mf := range families {
+		if mf.GetName() == "clock_http_client_request_duration_seconds" {
+			sum = mf.GetMetric()[0].GetHistogram().GetSampleSum()
+		}
+	}
+	assert.Equal(float64(2), sum)
+}
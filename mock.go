@@ -0,0 +1,221 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrMockNotMatched the request doesn't match any mock in strict mode
+	ErrMockNotMatched = errors.New("request doesn't match any mock")
+	// ErrMockResponseNil the responder of mock returns nil response without error
+	ErrMockResponseNil = errors.New("response of mock is nil")
+)
+
+type (
+	// MockResponder the function to create the response of mocked request
+	MockResponder func(*http.Request) (*http.Response, error)
+
+	mockRule struct {
+		method    string
+		host      string
+		path      string
+		responder MockResponder
+	}
+	mockTransport struct {
+		base http.RoundTripper
+	}
+)
+
+var (
+	mockRules  []*mockRule
+	mockStrict bool
+	mockLock   sync.RWMutex
+)
+
+// parseMockPattern parse the pattern of mock, the format is
+// "[METHOD ]host[/path]", the host and path support glob(path.Match),
+// e.g. "GET aslant.site/users/*" or "*.aslant.site"
+func parseMockPattern(pattern string) (*mockRule, error) {
+	rule := &mockRule{
+		method: "*",
+	}
+	fields := strings.Fields(pattern)
+	switch len(fields) {
+	case 1:
+		pattern = fields[0]
+	case 2:
+		rule.method = strings.ToUpper(fields[0])
+		pattern = fields[1]
+	default:
+		return nil, fmt.Errorf("mock pattern %q is invalid", pattern)
+	}
+	rule.host = pattern
+	// 未指定 path 则匹配所有
+	if index := strings.Index(pattern, "/"); index != -1 {
+		rule.host = pattern[:index]
+		rule.path = pattern[index:]
+	}
+	for _, p := range []string{rule.method, rule.host, rule.path} {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("mock pattern %q is invalid, %w", pattern, err)
+		}
+	}
+	return rule, nil
+}
+
+func (rule *mockRule) match(req *http.Request) bool {
+	if ok, _ := path.Match(rule.method, req.Method); !ok {
+		return false
+	}
+	if ok, _ := path.Match(rule.host, req.URL.Host); !ok {
+		return false
+	}
+	if rule.path == "" {
+		return true
+	}
+	reqPath := req.URL.Path
+	if reqPath == "" {
+		reqPath = "/"
+	}
+	ok, _ := path.Match(rule.path, reqPath)
+	return ok
+}
+
+// Mock add the mock of pattern, the request matches the pattern gets
+// the response of responder without sending to network.
+// The format of pattern is "[METHOD ]host[/path]" and glob is supported,
+// e.g. "GET aslant.site/users/*". The mock added earlier is matched first.
+func Mock(pattern string, responder MockResponder) error {
+	rule, err := parseMockPattern(pattern)
+	if err != nil {
+		return err
+	}
+	rule.responder = responder
+	mockLock.Lock()
+	defer mockLock.Unlock()
+	mockRules = append(mockRules, rule)
+	return nil
+}
+
+// MockOff remove all mocks and disable the strict mode
+func MockOff() {
+	mockLock.Lock()
+	defer mockLock.Unlock()
+	mockRules = nil
+	mockStrict = false
+}
+
+// SetMockStrict set the strict mode of mock, the request which doesn't match
+// any mock gets ErrMockNotMatched in strict mode, otherwise it's sent as usual
+func SetMockStrict(strict bool) {
+	mockLock.Lock()
+	defer mockLock.Unlock()
+	mockStrict = strict
+}
+
+// isMockEnabled check whether mock is enabled
+func isMockEnabled() bool {
+	mockLock.RLock()
+	defer mockLock.RUnlock()
+	return len(mockRules) != 0 || mockStrict
+}
+
+// getMockResponder get the responder of matched mock
+func getMockResponder(req *http.Request) (responder MockResponder, strict bool) {
+	mockLock.RLock()
+	defer mockLock.RUnlock()
+	for _, rule := range mockRules {
+		if rule.match(req) {
+			return rule.responder, mockStrict
+		}
+	}
+	return nil, mockStrict
+}
+
+// RoundTrip get the response from the matched mock
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	responder, strict := getMockResponder(req)
+	if responder == nil {
+		if strict {
+			return nil, fmt.Errorf("%w: %s %s", ErrMockNotMatched, req.Method, req.URL.String())
+		}
+		return t.base.RoundTrip(req)
+	}
+	resp, err := responder(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrMockResponseNil, req.Method, req.URL.String())
+	}
+	if resp.Body == nil {
+		resp.Body = http.NoBody
+	}
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+	if resp.Status == "" {
+		resp.Status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+// MockJSON create the responder which responds the json of v with status
+func MockJSON(status int, v interface{}) MockResponder {
+	return func(_ *http.Request) (*http.Response, error) {
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return NewMockResponse(status, MIMEApplicationJSON, buf), nil
+	}
+}
+
+// MockString create the responder which responds the text with status
+func MockString(status int, text string) MockResponder {
+	return func(_ *http.Request) (*http.Response, error) {
+		return NewMockResponse(status, MIMETextPlain, []byte(text)), nil
+	}
+}
+
+// NewMockResponse create the response of mock
+func NewMockResponse(status int, contentType string, body []byte) *http.Response {
+	header := make(http.Header)
+	if contentType != "" {
+		header.Set(HeaderContentType, contentType)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
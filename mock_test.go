@@ -0,0 +1,119 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMockPattern(t *testing.T) {
+	assert := assert.New(t)
+	rule, err := parseMockPattern("get aslant.site/users/*")
+	assert.Nil(err)
+	assert.Equal("GET", rule.method)
+	assert.Equal("aslant.site", rule.host)
+	assert.Equal("/users/*", rule.path)
+
+	rule, err = parseMockPattern("*.aslant.site")
+	assert.Nil(err)
+	assert.Equal("*", rule.method)
+	assert.Equal("*.aslant.site", rule.host)
+	assert.Empty(rule.path)
+
+	_, err = parseMockPattern("GET aslant.site /users")
+	assert.NotNil(err)
+	_, err = parseMockPattern("aslant.site/[a")
+	assert.NotNil(err)
+}
+
+func TestMock(t *testing.T) {
+	defer MockOff()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("network"))
+	}))
+	defer ts.Close()
+
+	assert.Nil(t, Mock("GET aslant.site/users/*", MockJSON(http.StatusOK, map[string]string{
+		"name": "tree.xie",
+	})))
+	assert.Nil(t, Mock("*.aslant.site", MockString(http.StatusCreated, "sub domain")))
+	e := errors.New("responder fail")
+	assert.Nil(t, Mock("POST aslant.site/error", func(_ *http.Request) (*http.Response, error) {
+		return nil, e
+	}))
+	assert.Nil(t, Mock("POST aslant.site/nil", func(_ *http.Request) (*http.Response, error) {
+		return nil, nil
+	}))
+
+	t.Run("match", func(t *testing.T) {
+		assert := assert.New(t)
+		data := struct {
+			Name string `json:"name"`
+		}{}
+		resp, body, err := Get("http://aslant.site/users/1").
+			Into(&data).
+			Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal(MIMEApplicationJSON, resp.Header.Get(HeaderContentType))
+		assert.Equal(`{"name":"tree.xie"}`, string(body))
+		assert.Equal("tree.xie", data.Name)
+
+		resp, body, err = Post("https://api.aslant.site/").Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusCreated, resp.StatusCode)
+		assert.Equal("201 Created", resp.Status)
+		assert.Equal("sub domain", string(body))
+
+		_, _, err = Post("http://aslant.site/error").Do()
+		assert.True(errors.Is(err, e))
+
+		// 返回的响应为 nil 时不 panic
+		_, _, err = Post("http://aslant.site/nil").Do()
+		assert.True(errors.Is(err, ErrMockResponseNil))
+	})
+
+	t.Run("pass through", func(t *testing.T) {
+		assert := assert.New(t)
+		_, body, err := Get(ts.URL).Do()
+		assert.Nil(err)
+		assert.Equal("network", string(body))
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		assert := assert.New(t)
+		SetMockStrict(true)
+		defer SetMockStrict(false)
+		_, _, err := Get(ts.URL).Do()
+		assert.True(errors.Is(err, ErrMockNotMatched))
+		// 方法不匹配
+		_, _, err = Post("http://aslant.site/users/1").Do()
+		assert.True(errors.Is(err, ErrMockNotMatched))
+	})
+
+	t.Run("off", func(t *testing.T) {
+		assert := assert.New(t)
+		MockOff()
+		assert.False(isMockEnabled())
+		_, body, err := Get(ts.URL).Do()
+		assert.Nil(err)
+		assert.Equal("network", string(body))
+	})
+}
@@ -0,0 +1,249 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+type (
+	// NamingConvention the naming convention of json key
+	NamingConvention int
+
+	// jsonField the field of struct for json key
+	jsonField struct {
+		value  reflect.Value
+		tagged bool
+	}
+)
+
+const (
+	// SnakeCase snake case, e.g. user_name
+	SnakeCase NamingConvention = 1 << iota
+	// CamelCase camel case, e.g. userName
+	CamelCase
+	// KebabCase kebab case, e.g. user-name
+	KebabCase
+	// RespectTags keep the key of the field which has json tag,
+	// it should be combined with other naming convention, e.g. SnakeCase|RespectTags
+	RespectTags
+)
+
+// SendWithNaming set the send data, the keys of marshaled json will be
+// converted to the naming convention(including nested objects and arrays).
+// It returns error when do request if two keys are converted to the same key.
+func (d *Dusk) SendWithNaming(v interface{}, naming NamingConvention) *Dusk {
+	buf, err := marshalWithNaming(v, naming)
+	if err != nil {
+		d.buildErr = err
+		return d
+	}
+	return d.Send(json.RawMessage(buf))
+}
+
+func marshalWithNaming(v interface{}, naming NamingConvention) ([]byte, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	// 避免数字转换为 float64 时丢失精度
+	decoder.UseNumber()
+	var data interface{}
+	err = decoder.Decode(&data)
+	if err != nil {
+		return nil, err
+	}
+	data, err = convertNaming(data, reflect.ValueOf(v), naming)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(data)
+}
+
+// indirectValue get the value which pointer or interface points to
+func indirectValue(rv reflect.Value) reflect.Value {
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// getJSONFields get the fields of struct by json key,
+// the fields of embedded struct are included.
+func getJSONFields(rv reflect.Value, fields map[string]jsonField) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		value := rv.Field(i)
+		// 未指定名称的嵌入 struct，其字段提升至当前层级
+		if field.Anonymous && name == "" {
+			embedded := indirectValue(value)
+			if embedded.IsValid() && embedded.Kind() == reflect.Struct {
+				embeddedFields := make(map[string]jsonField)
+				getJSONFields(embedded, embeddedFields)
+				for k, f := range embeddedFields {
+					if _, exists := fields[k]; !exists {
+						fields[k] = f
+					}
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = field.Name
+		}
+		fields[name] = jsonField{
+			value:  value,
+			tagged: tagged,
+		}
+	}
+}
+
+// convertNaming convert the keys of data to naming convention,
+// the rv is the original value of data, it's used to check the json tag.
+func convertNaming(data interface{}, rv reflect.Value, naming NamingConvention) (interface{}, error) {
+	rv = indirectValue(rv)
+	switch v := data.(type) {
+	case map[string]interface{}:
+		var fields map[string]jsonField
+		if rv.IsValid() && rv.Kind() == reflect.Struct {
+			fields = make(map[string]jsonField)
+			getJSONFields(rv, fields)
+		}
+		result := make(map[string]interface{}, len(v))
+		// 记录转换前的 key，用于判断是否有冲突
+		originalKeys := make(map[string]string, len(v))
+		for key, value := range v {
+			var child reflect.Value
+			tagged := false
+			if fields != nil {
+				field := fields[key]
+				child = field.value
+				tagged = field.tagged
+			} else if rv.IsValid() && rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+				child = rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+			}
+			newKey := key
+			if !tagged || naming&RespectTags == 0 {
+				newKey = convertKey(key, naming)
+			}
+			if prev, exists := originalKeys[newKey]; exists {
+				return nil, fmt.Errorf("naming conflict: %q and %q are both converted to %q", prev, key, newKey)
+			}
+			originalKeys[newKey] = key
+			newValue, err := convertNaming(value, child, naming)
+			if err != nil {
+				return nil, err
+			}
+			result[newKey] = newValue
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			var child reflect.Value
+			if rv.IsValid() && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && i < rv.Len() {
+				child = rv.Index(i)
+			}
+			newValue, err := convertNaming(value, child, naming)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = newValue
+		}
+		return result, nil
+	}
+	return data, nil
+}
+
+// splitWords split the key to words,
+// e.g. userID -> [user ID], user_name -> [user name]
+func splitWords(key string) []string {
+	runes := []rune(key)
+	words := make([]string, 0)
+	start := -1
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == ' ' {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		prev := runes[i-1]
+		// 小写（或数字）转大写，如 userName
+		// 连续大写后接小写，如 HTTPServer 中的 S
+		if unicode.IsUpper(r) &&
+			(!unicode.IsUpper(prev) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// convertKey convert the key to naming convention
+func convertKey(key string, naming NamingConvention) string {
+	// 保留前缀的下划线，如 _id
+	prefix := key[:len(key)-len(strings.TrimLeft(key, "_"))]
+	words := splitWords(key)
+	if len(words) == 0 {
+		return key
+	}
+	switch {
+	case naming&SnakeCase != 0:
+		return prefix + strings.ToLower(strings.Join(words, "_"))
+	case naming&KebabCase != 0:
+		return prefix + strings.ToLower(strings.Join(words, "-"))
+	case naming&CamelCase != 0:
+		for i, word := range words {
+			word = strings.ToLower(word)
+			if i != 0 {
+				runes := []rune(word)
+				runes[0] = unicode.ToUpper(runes[0])
+				word = string(runes)
+			}
+			words[i] = word
+		}
+		return prefix + strings.Join(words, "")
+	}
+	return key
+}
@@ -0,0 +1,215 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	// snakePayload the payload with snake case keys for property test
+	snakePayload map[string]interface{}
+)
+
+// randomSnakeKey generate snake case key, the word has two letters at least,
+// because single letter words can't round trip, e.g. a_b_c -> aBC -> a_bc
+func randomSnakeKey(r *rand.Rand) string {
+	count := r.Intn(3) + 1
+	words := make([]string, count)
+	for i := range words {
+		b := make([]byte, r.Intn(5)+2)
+		for j := range b {
+			b[j] = byte('a' + r.Intn(26))
+		}
+		words[i] = string(b)
+	}
+	return strings.Join(words, "_")
+}
+
+func randomSnakeValue(r *rand.Rand, depth int) interface{} {
+	n := 4
+	if depth <= 0 {
+		n = 2
+	}
+	switch r.Intn(n) {
+	case 0:
+		return float64(r.Intn(1000))
+	case 1:
+		return randomSnakeKey(r)
+	case 2:
+		arr := make([]interface{}, r.Intn(3))
+		for i := range arr {
+			arr[i] = randomSnakeValue(r, depth-1)
+		}
+		return arr
+	default:
+		return map[string]interface{}(randomSnakePayload(r, depth-1))
+	}
+}
+
+func randomSnakePayload(r *rand.Rand, depth int) snakePayload {
+	m := make(snakePayload)
+	count := r.Intn(4) + 1
+	for i := 0; i < count; i++ {
+		m[randomSnakeKey(r)] = randomSnakeValue(r, depth)
+	}
+	return m
+}
+
+// Generate generate random payload for quick check
+func (snakePayload) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(randomSnakePayload(r, 3))
+}
+
+func TestConvertKey(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]string{"user", "ID"}, splitWords("userID"))
+	assert.Equal([]string{"HTTP", "Server"}, splitWords("HTTPServer"))
+	assert.Equal([]string{"address1", "Line"}, splitWords("address1Line"))
+
+	assert.Equal("user_name", convertKey("UserName", SnakeCase))
+	assert.Equal("http_server", convertKey("HTTPServer", SnakeCase))
+	assert.Equal("_id", convertKey("_id", SnakeCase))
+	assert.Equal("userName", convertKey("user_name", CamelCase))
+	assert.Equal("userName", convertKey("user-name", CamelCase))
+	assert.Equal("user-name", convertKey("userName", KebabCase))
+	assert.Equal("userName", convertKey("userName", 0))
+}
+
+func TestMarshalWithNaming(t *testing.T) {
+	type Address struct {
+		StreetName string
+		ZipCode    string `json:"zip"`
+	}
+	type User struct {
+		Address
+		UserName  string
+		CreatedAt int64 `json:"createdAt"`
+		Addresses []Address
+		ignored   string
+	}
+	user := &User{
+		Address: Address{
+			StreetName: "a",
+			ZipCode:    "1",
+		},
+		UserName:  "tree.xie",
+		CreatedAt: 1,
+		Addresses: []Address{
+			{
+				StreetName: "b",
+				ZipCode:    "2",
+			},
+		},
+	}
+
+	t.Run("snake case", func(t *testing.T) {
+		assert := assert.New(t)
+		buf, err := marshalWithNaming(user, SnakeCase)
+		assert.Nil(err)
+		assert.Equal(`{"addresses":[{"street_name":"b","zip":"2"}],"created_at":1,"street_name":"a","user_name":"tree.xie","zip":"1"}`, string(buf))
+	})
+
+	t.Run("respect tags", func(t *testing.T) {
+		assert := assert.New(t)
+		buf, err := marshalWithNaming(user, KebabCase|RespectTags)
+		assert.Nil(err)
+		assert.Equal(`{"addresses":[{"street-name":"b","zip":"2"}],"createdAt":1,"street-name":"a","user-name":"tree.xie","zip":"1"}`, string(buf))
+	})
+
+	t.Run("keep number precision", func(t *testing.T) {
+		assert := assert.New(t)
+		buf, err := marshalWithNaming(map[string]interface{}{
+			"userId": int64(9007199254740993),
+		}, SnakeCase)
+		assert.Nil(err)
+		assert.Equal(`{"user_id":9007199254740993}`, string(buf))
+	})
+
+	t.Run("key conflict", func(t *testing.T) {
+		assert := assert.New(t)
+		_, err := marshalWithNaming(map[string]interface{}{
+			"data": []interface{}{
+				map[string]interface{}{
+					"userName":  1,
+					"user_name": 2,
+				},
+			},
+		}, SnakeCase)
+		assert.NotNil(err)
+		assert.True(strings.Contains(err.Error(), `converted to "user_name"`))
+	})
+}
+
+func TestMarshalWithNamingRoundTrip(t *testing.T) {
+	roundTrip := func(naming NamingConvention) func(snakePayload) bool {
+		return func(payload snakePayload) bool {
+			buf, err := marshalWithNaming(payload, naming)
+			if err != nil {
+				return false
+			}
+			var converted interface{}
+			err = json.Unmarshal(buf, &converted)
+			if err != nil {
+				return false
+			}
+			buf, err = marshalWithNaming(converted, SnakeCase)
+			if err != nil {
+				return false
+			}
+			expected, _ := json.Marshal(payload)
+			return string(expected) == string(buf)
+		}
+	}
+	assert := assert.New(t)
+	assert.Nil(quick.Check(roundTrip(CamelCase), nil))
+	assert.Nil(quick.Check(roundTrip(KebabCase), nil))
+	assert.Nil(quick.Check(roundTrip(SnakeCase), nil))
+}
+
+func TestSendWithNaming(t *testing.T) {
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		w.Write(buf)
+	}))
+	defer ts.Close()
+
+	_, body, err := Post(ts.URL).
+		SendWithNaming(map[string]string{
+			"userName": "tree.xie",
+		}, SnakeCase).
+		Do()
+	assert.Nil(err)
+	assert.Equal(`{"user_name":"tree.xie"}`, string(body))
+
+	_, _, err = Post(ts.URL).
+		SendWithNaming(map[string]string{
+			"userName":  "tree.xie",
+			"user_name": "tree.xie",
+		}, SnakeCase).
+		Do()
+	assert.NotNil(err)
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// maxErrorBodySize the max size of body read for ResponseError in pipe mode
+	maxErrorBodySize = 64 * 1024
+)
+
+var (
+	// ErrPipeDecode the response can't be decoded in pipe mode
+	ErrPipeDecode = errors.New("content decoding is not supported in pipe mode, only gzip can be streamed")
+)
+
+// Pipe copy the response body to the writer instead of buffering,
+// the Body of dusk will be nil. The response before listeners get the
+// live body, and the after listeners can get the count of bytes
+// by GetBytesWritten.
+func (d *Dusk) Pipe(w io.Writer) *Dusk {
+	d.pipeWriter = w
+	return d
+}
+
+// GetBytesWritten get the number of bytes written to the pipe writer
+func (d *Dusk) GetBytesWritten() int64 {
+	return d.bytesWritten
+}
+
+func (d *Dusk) isPipeMode() bool {
+	return d.pipeWriter != nil
+}
+
+// pipeBody copy the response body to pipe writer
+func (d *Dusk) pipeBody(resp *http.Response) (err error) {
+	var r io.Reader = resp.Body
+	total := resp.ContentLength
+	// 如果手工设置了 Accept-Encoding，http transport 不会自动解压 gzip，
+	// gzip 可以流式解压，因此在复制数据时解压
+	if resp.Header.Get(HeaderContentEncoding) == GzipEncoding {
+		// 解压后的数据长度未知
+		total = -1
+		gr, err := newGzipStreamReader(resp)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	w := d.pipeWriter
+	if d.downloadProgress != nil {
+		pw := newProgressWriter(w, total, d.downloadProgress)
+		defer pw.finish()
+		w = pw
+	}
+	d.bytesWritten, err = io.Copy(w, r)
+	return
+}
+
+// newGzipStreamReader create a gzip reader of response body,
+// and remove the encoding headers as the data will be decompressed
+func newGzipStreamReader(resp *http.Response) (*gzip.Reader, error) {
+	gr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Uncompressed = true
+	resp.Header.Del(HeaderContentEncoding)
+	resp.Header.Del(HeaderContentLength)
+	return gr, nil
+}
+
+// OnProgress set the download progress listener, it will be called every
+// time 64KB data is written in pipe mode, the total is the Content-Length
+// of response, it will be -1 if it's unknown.
+func (d *Dusk) OnProgress(fn ProgressListener) *Dusk {
+	d.downloadProgress = fn
+	return d
+}
+
+// SaveToFile do http request and save the response body to file,
+// the parent directories will be created and the data is written to
+// a temp file and renamed to the path on success, the temp file will
+// be removed if error occurs. The ResponseError will be returned and
+// the file won't be created if the status code isn't 2xx.
+func (d *Dusk) SaveToFile(path string) (written int64, err error) {
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	tmpFile := f.Name()
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmpFile)
+		}
+	}()
+	// 非 2xx 的响应不写入文件
+	d.AddResponseListener(func(resp *http.Response, d *Dusk) error {
+		if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+			return nil
+		}
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return &ResponseError{
+			StatusCode: resp.StatusCode,
+			Body:       body,
+			Method:     d.GetMethod(),
+			URL:        d.Request.URL.String(),
+		}
+	}, EventTypeBefore)
+	_, _, err = d.Pipe(f).Do()
+	if err != nil {
+		return
+	}
+	written = d.GetBytesWritten()
+	err = f.Close()
+	if err != nil {
+		return
+	}
+	err = os.Rename(tmpFile, path)
+	return
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipe(t *testing.T) {
+	data := bytes.Repeat([]byte("abcd"), 1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gzip" {
+			w.Header().Set(HeaderContentEncoding, GzipEncoding)
+			gw := gzip.NewWriter(w)
+			gw.Write(data)
+			gw.Close()
+			return
+		}
+		if r.URL.Path == "/br" {
+			w.Header().Set(HeaderContentEncoding, BrEncoding)
+		}
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	t.Run("pipe to writer", func(t *testing.T) {
+		assert := assert.New(t)
+		b := new(bytes.Buffer)
+		var written int64
+		d := Get(ts.URL).Pipe(b)
+		d.AddResponseListener(func(_ *http.Response, d *Dusk) error {
+			written = d.GetBytesWritten()
+			return nil
+		}, EventTypeAfter)
+		resp, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Nil(body)
+		assert.Nil(d.Body)
+		assert.Equal(data, b.Bytes())
+		assert.Equal(int64(len(data)), written)
+		assert.Equal(int64(len(data)), d.GetBytesWritten())
+	})
+
+	t.Run("pipe gzip response", func(t *testing.T) {
+		assert := assert.New(t)
+		b := new(bytes.Buffer)
+		d := Get(ts.URL+"/gzip").
+			Set(HeaderAcceptEncoding, GzipEncoding).
+			Pipe(b)
+		resp, _, err := d.Do()
+		assert.Nil(err)
+		assert.Empty(resp.Header.Get(HeaderContentEncoding))
+		assert.Equal(data, b.Bytes())
+		assert.Equal(int64(len(data)), d.GetBytesWritten())
+	})
+
+	t.Run("decoder is not supported", func(t *testing.T) {
+		assert := assert.New(t)
+		b := new(bytes.Buffer)
+		_, _, err := Get(ts.URL + "/br").
+			Br().
+			Pipe(b).
+			Do()
+		assert.Equal(ErrPipeDecode, err)
+		assert.Equal(0, b.Len())
+	})
+}
+
+func TestSaveToFile(t *testing.T) {
+	size := 200 * 1024
+	data := bytes.Repeat([]byte("a"), size)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderContentLength, strconv.Itoa(size))
+		if r.URL.Path == "/error" {
+			// 只返回部分数据，读取时出错
+			w.Write(data[:1024])
+			return
+		}
+		if r.URL.Path == "/not-found" {
+			w.Header().Del(HeaderContentLength)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+			return
+		}
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	t.Run("save to file", func(t *testing.T) {
+		assert := assert.New(t)
+		dir, err := ioutil.TempDir("", "dusk")
+		assert.Nil(err)
+		defer os.RemoveAll(dir)
+
+		// 父目录不存在时自动创建
+		file := filepath.Join(dir, "artifacts", "data.txt")
+		var written, total int64
+		count := 0
+		n, err := Get(ts.URL).
+			OnProgress(func(w, t int64) {
+				count++
+				written = w
+				total = t
+			}).
+			SaveToFile(file)
+		assert.Nil(err)
+		assert.Equal(int64(size), n)
+		buf, err := ioutil.ReadFile(file)
+		assert.Nil(err)
+		assert.Equal(data, buf)
+		assert.Equal(int64(size), written)
+		assert.Equal(int64(size), total)
+		assert.True(count > 1 && count <= size/progressGranularity+1)
+
+		files, _ := ioutil.ReadDir(filepath.Dir(file))
+		assert.Equal(1, len(files))
+	})
+
+	t.Run("remove temp file on error", func(t *testing.T) {
+		assert := assert.New(t)
+		dir, err := ioutil.TempDir("", "dusk")
+		assert.Nil(err)
+		defer os.RemoveAll(dir)
+
+		file := filepath.Join(dir, "data.txt")
+		_, err = Get(ts.URL + "/error").SaveToFile(file)
+		assert.NotNil(err)
+		files, _ := ioutil.ReadDir(dir)
+		assert.Equal(0, len(files))
+	})
+
+	t.Run("not 2xx", func(t *testing.T) {
+		assert := assert.New(t)
+		dir, err := ioutil.TempDir("", "dusk")
+		assert.Nil(err)
+		defer os.RemoveAll(dir)
+
+		file := filepath.Join(dir, "data.txt")
+		n, err := Get(ts.URL + "/not-found").SaveToFile(file)
+		assert.Equal(int64(0), n)
+		re, ok := err.(*ResponseError)
+		assert.True(ok)
+		assert.Equal(http.StatusNotFound, re.StatusCode)
+		assert.Equal("not found", string(re.Body))
+		files, _ := ioutil.ReadDir(dir)
+		assert.Equal(0, len(files))
+	})
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import "sync"
+
+var duskPool = sync.Pool{
+	New: func() interface{} {
+		return &Dusk{}
+	},
+}
+
+// NewWithPool get a dusk from pool and init it with method and url
+// as Get/Post do(the global listeners are added in init, so the method
+// and url can't be set after getting from pool),
+// it should be put back by PutPool after the response is handled,
+// and then the dusk (including Request, Response and Body) can't be used any more.
+func NewWithPool(method, url string) *Dusk {
+	d := duskPool.Get().(*Dusk)
+	d.Reset()
+	d.init(method, url)
+	return d
+}
+
+// PutPool close and reset the dusk, then put it back to pool
+func PutPool(d *Dusk) {
+	if d == nil {
+		return
+	}
+	d.Close()
+	d.Reset()
+	duskPool.Put(d)
+}
+
+// Reset reset all fields of dusk to zero value
+func (d *Dusk) Reset() {
+	*d = Dusk{}
+}
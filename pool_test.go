@@ -0,0 +1,97 @@
+package dusk
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestNewWithPool(t *testing.T) {
+	assert := assert.New(t)
+	defer gock.Off()
+	gock.New("http://aslant.site").
+		Get("/").
+		Reply(200).
+		BodyString(`{"name":"tree.xie"}`)
+
+	d := NewWithPool(http.MethodGet, "http://aslant.site/")
+	d.Set("X-Token", "abc")
+	_, body, err := d.Do()
+	assert.Nil(err)
+	assert.Equal(`{"name":"tree.xie"}`, string(body))
+	PutPool(d)
+	PutPool(nil)
+
+	d = NewWithPool(http.MethodPost, "http://aslant.site/users")
+	assert.Equal(http.MethodPost, d.method)
+	assert.Equal("http://aslant.site/users", d.GetURL())
+	assert.Nil(d.Body)
+	assert.Nil(d.Response)
+	assert.Empty(d.header.Get("X-Token"))
+}
+
+func TestReset(t *testing.T) {
+	assert := assert.New(t)
+	d := Get("http://aslant.site/").
+		Query("type", "1").
+		Timeout(time.Second)
+	d.Reset()
+	assert.Empty(d.GetURL())
+	assert.Equal(time.Duration(0), d.timeout)
+	assert.Nil(d.query)
+}
+
+// benchDusk 避免 benchmark 中的 dusk 被分配在栈上
+var benchDusk *Dusk
+
+func BenchmarkNewVsPool(b *testing.B) {
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			d := Get("http://aslant.site/")
+			d.Set("X-Token", "abc")
+			benchDusk = d
+		}
+	})
+	b.Run("pool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			d := NewWithPool(http.MethodGet, "http://aslant.site/")
+			d.Set("X-Token", "abc")
+			benchDusk = d
+			PutPool(d)
+		}
+	})
+}
+
+func TestPutPoolWithCache(t *testing.T) {
+	assert := assert.New(t)
+	defer gock.Off()
+	defer ClearRequestCache()
+	gock.New("http://aslant.site").
+		Get("/cache").
+		Reply(200).
+		BodyString("ok")
+
+	d := NewWithPool(http.MethodGet, "http://aslant.site/cache")
+	d.ExpireAfter(time.Minute)
+	_, _, err := d.Do()
+	assert.Nil(err)
+	key := d.getCacheKey()
+	PutPool(d)
+
+	// 缓存仅保存状态码、header 与数据，不再引用已回收的 dusk
+	entry, ok := requestCache.Get(key)
+	assert.True(ok)
+	assert.Equal(http.StatusOK, entry.statusCode)
+	assert.Equal("ok", string(entry.body))
+
+	d = Get("http://aslant.site/cache").ExpireAfter(time.Minute)
+	resp, body, err := d.Do()
+	assert.Nil(err)
+	assert.Equal("ok", string(body))
+	assert.Equal(d.Request, resp.Request)
+}
@@ -0,0 +1,189 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPrefetchConcurrency = 2
+)
+
+type (
+	// PrefetchOptions the options of prefetch
+	PrefetchOptions struct {
+		// Concurrency the max count of concurrent prefetch requests, default is 2
+		Concurrency int
+		// TTL the cache ttl of prefetch response, default is the ttl of instance
+		TTL time.Duration
+		// Timeout the timeout of each prefetch request
+		Timeout time.Duration
+		// OnError the function called when prefetch fails,
+		// the error is ignored if it's not set
+		OnError func(url string, err error)
+	}
+	// PrefetchHandle the handle of prefetch, it can be used to
+	// cancel the prefetch requests or wait for them done
+	PrefetchHandle struct {
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+)
+
+var (
+	// ErrPrefetchNotCacheable the prefetch request isn't cacheable(e.g. ttl isn't set)
+	ErrPrefetchNotCacheable = errors.New("prefetch request isn't cacheable")
+	// prefetchInflight the in-flight prefetch requests,
+	// key: cache key, value: the channel closed when it's done
+	prefetchInflight sync.Map
+)
+
+// Cancel cancel all prefetch requests which are not done
+func (h *PrefetchHandle) Cancel() {
+	h.cancel()
+}
+
+// Wait wait until all prefetch requests are done
+func (h *PrefetchHandle) Wait() {
+	<-h.done
+}
+
+// IsPrefetch check whether the request is created by prefetch,
+// the done listeners can use it to skip the background requests
+func (d *Dusk) IsPrefetch() bool {
+	return d.prefetch
+}
+
+// waitPrefetch wait for the in-flight prefetch of the same request,
+// it returns true if the response is got from cache after prefetch done.
+func (d *Dusk) waitPrefetch() bool {
+	if d.cacheTTL <= 0 || !d.isCacheable() {
+		return false
+	}
+	v, ok := prefetchInflight.Load(d.getCacheKey())
+	if !ok {
+		return false
+	}
+	select {
+	case <-v.(chan struct{}):
+	case <-d.Request.Context().Done():
+		return false
+	}
+	return d.getFromCache()
+}
+
+// startPrefetch mark the prefetch request as in-flight after the cache
+// is missed, so the requests of the same cache key wait for it.
+func (d *Dusk) startPrefetch() {
+	if !d.prefetch {
+		return
+	}
+	key := d.getCacheKey()
+	done := make(chan struct{})
+	// 相同的请求已在预取中（并发时），直接发送
+	if _, loaded := prefetchInflight.LoadOrStore(key, done); loaded {
+		return
+	}
+	d.prefetchDone = func() {
+		prefetchInflight.Delete(key)
+		close(done)
+	}
+}
+
+// finishPrefetch notify the waiting requests that the prefetch is done,
+// it should be called after the response is saved to cache.
+func (d *Dusk) finishPrefetch() {
+	if d.prefetchDone != nil {
+		d.prefetchDone()
+		d.prefetchDone = nil
+	}
+}
+
+// Prefetch get the urls in background and save the responses to cache,
+// so the following get requests of the same url will hit the cache.
+// The prefetch requests are sent with low priority hint, the rate limit
+// and listeners of instance are applied except the error listeners,
+// the failures are only passed to OnError of options. The prefetch requests
+// are marked by IsPrefetch, they aren't counted by the circuit breaker.
+// The url which is cached or being prefetched will be skipped, and the get
+// request of the same url waits for the in-flight prefetch.
+func (ins *Instance) Prefetch(urls []string, opts PrefetchOptions) *PrefetchHandle {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &PrefetchHandle{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultPrefetchConcurrency
+	}
+	onError := opts.OnError
+	go func() {
+		defer close(h.done)
+		defer cancel()
+		limit := make(chan struct{}, concurrency)
+		wg := sync.WaitGroup{}
+		for _, url := range urls {
+			select {
+			case <-ctx.Done():
+			case limit <- struct{}{}:
+			}
+			// 已取消则不再发送
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			go func(url string) {
+				defer wg.Done()
+				defer func() {
+					<-limit
+				}()
+				err := ins.prefetch(ctx, url, opts)
+				if err != nil && onError != nil {
+					onError(url, err)
+				}
+			}(url)
+		}
+		wg.Wait()
+	}()
+	return h
+}
+
+// prefetch get the url and save the response to cache
+func (ins *Instance) prefetch(ctx context.Context, url string, opts PrefetchOptions) error {
+	d := ins.Get(url)
+	d.prefetch = true
+	// 预取的出错不触发 error listener，避免影响出错统计
+	d.errorListeners = nil
+	d.errorListenerIDs = nil
+	if opts.TTL > 0 {
+		d.ExpireAfter(opts.TTL)
+	}
+	if opts.Timeout > 0 {
+		d.Timeout(opts.Timeout)
+	}
+	if !d.isCacheable() {
+		return ErrPrefetchNotCacheable
+	}
+	// 已缓存或正在预取的在查找缓存时跳过，不再发送
+	_, _, err := d.SetContext(ctx).
+		Set(HeaderPriorityHint, PriorityLow).
+		Do()
+	return err
+}
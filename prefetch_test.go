@@ -0,0 +1,220 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefetch(t *testing.T) {
+	defer ClearRequestCache()
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(r.Header.Get(HeaderPriorityHint) + r.URL.Path))
+	}))
+	defer ts.Close()
+
+	t.Run("save to cache", func(t *testing.T) {
+		assert := assert.New(t)
+		ins := NewInstanceWithConfig(Config{
+			BaseURL: ts.URL,
+		}).ExpireAfter(time.Minute)
+		h := ins.Prefetch([]string{
+			"/a",
+			"/b",
+			"/a",
+		}, PrefetchOptions{})
+		h.Wait()
+		assert.Equal(int32(2), atomic.LoadInt32(&hits))
+
+		_, body, err := ins.Get("/a").Do()
+		assert.Nil(err)
+		assert.Equal("low/a", string(body))
+		assert.Equal(int32(2), atomic.LoadInt32(&hits))
+
+		// 已缓存的不再预取
+		ins.Prefetch([]string{
+			"/b",
+		}, PrefetchOptions{}).Wait()
+		assert.Equal(int32(2), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("not cacheable", func(t *testing.T) {
+		assert := assert.New(t)
+		var errs []error
+		NewInstance().Prefetch([]string{
+			ts.URL,
+		}, PrefetchOptions{
+			OnError: func(_ string, err error) {
+				errs = append(errs, err)
+			},
+		}).Wait()
+		assert.Equal([]error{
+			ErrPrefetchNotCacheable,
+		}, errs)
+	})
+}
+
+func TestPrefetchError(t *testing.T) {
+	defer ClearRequestCache()
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	var listenerCalled int32
+	ins := NewInstance().
+		AddErrorListener(func(err error, _ *Dusk) error {
+			atomic.AddInt32(&listenerCalled, 1)
+			return err
+		})
+	var prefetchDone bool
+	ins.AddDoneListener(func(d *Dusk) error {
+		prefetchDone = d.IsPrefetch()
+		return nil
+	})
+	mu := sync.Mutex{}
+	failedURLs := make([]string, 0)
+	ins.Prefetch([]string{
+		url,
+	}, PrefetchOptions{
+		TTL: time.Minute,
+		OnError: func(url string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failedURLs = append(failedURLs, url)
+		},
+	}).Wait()
+	assert.Equal([]string{
+		url,
+	}, failedURLs)
+	assert.True(prefetchDone)
+	assert.Equal(int32(0), atomic.LoadInt32(&listenerCalled))
+}
+
+func TestPrefetchCancel(t *testing.T) {
+	defer ClearRequestCache()
+	assert := assert.New(t)
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	var failed int32
+	h := NewInstance().Prefetch([]string{
+		ts.URL + "/a",
+		ts.URL + "/b",
+		ts.URL + "/c",
+	}, PrefetchOptions{
+		Concurrency: 1,
+		TTL:         time.Minute,
+		OnError: func(_ string, _ error) {
+			atomic.AddInt32(&failed, 1)
+		},
+	})
+	// 等待第一个请求发出后取消
+	for atomic.LoadInt32(&hits) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	h.Cancel()
+	h.Wait()
+	assert.Equal(int32(1), atomic.LoadInt32(&hits))
+	assert.Equal(int32(1), atomic.LoadInt32(&failed))
+}
+
+func TestPrefetchShareInflight(t *testing.T) {
+	defer ClearRequestCache()
+	assert := assert.New(t)
+	var hits int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Write([]byte(r.Header.Get(HeaderPriorityHint)))
+	}))
+	defer ts.Close()
+
+	ins := NewInstance().ExpireAfter(time.Minute)
+	h := ins.Prefetch([]string{
+		ts.URL,
+	}, PrefetchOptions{})
+	for atomic.LoadInt32(&hits) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// 相同的请求等待预取完成后使用其缓存
+	done := make(chan []byte)
+	go func() {
+		_, body, err := ins.Get(ts.URL).Do()
+		assert.Nil(err)
+		done <- body
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	assert.Equal("low", string(<-done))
+	h.Wait()
+	assert.Equal(int32(1), atomic.LoadInt32(&hits))
+}
+
+type countCircuitBreaker struct {
+	allows    int32
+	successes int32
+}
+
+func (cb *countCircuitBreaker) Allow() bool {
+	atomic.AddInt32(&cb.allows, 1)
+	return true
+}
+
+func (cb *countCircuitBreaker) RecordSuccess() {
+	atomic.AddInt32(&cb.successes, 1)
+}
+
+func (cb *countCircuitBreaker) RecordFailure() {}
+
+func TestPrefetchCircuitBreaker(t *testing.T) {
+	defer ClearRequestCache()
+	assert := assert.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	cb := &countCircuitBreaker{}
+	ins := NewInstance().
+		ExpireAfter(time.Minute).
+		SetCircuitBreaker(cb)
+	// 未设置 OnError 时出错被忽略
+	ins.Prefetch([]string{
+		ts.URL,
+		"http://[::1",
+	}, PrefetchOptions{}).Wait()
+	assert.Equal(int32(0), atomic.LoadInt32(&cb.allows))
+	assert.Equal(int32(0), atomic.LoadInt32(&cb.successes))
+
+	_, _, err := ins.Get(ts.URL + "/a").Do()
+	assert.Nil(err)
+	assert.Equal(int32(1), atomic.LoadInt32(&cb.allows))
+	assert.Equal(int32(1), atomic.LoadInt32(&cb.successes))
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import "errors"
+
+const (
+	// HeaderPriorityHint priority hint header, it's used by some CDNs
+	HeaderPriorityHint = "Priority-Hint"
+
+	// PriorityHigh high priority
+	PriorityHigh = "high"
+	// PriorityNormal normal priority
+	PriorityNormal = "normal"
+	// PriorityLow low priority
+	PriorityLow = "low"
+
+	minH2Weight     = 1
+	maxH2Weight     = 256
+	defaultH2Weight = 16
+)
+
+var (
+	// ErrH2WeightInvalid the weight of http2 priority is out of range
+	ErrH2WeightInvalid = errors.New("weight of http2 priority should be between 1 and 256")
+)
+
+type (
+	// H2Priority http2 stream priority
+	H2Priority struct {
+		// Weight the weight of stream, 1-256
+		Weight int
+		// Exclusive whether the stream dependency is exclusive
+		Exclusive bool
+		// Dependency the stream id of dependency
+		Dependency uint32
+	}
+)
+
+// Hint get the priority hint of weight,
+// the default weight(16) is normal
+func (p *H2Priority) Hint() string {
+	switch {
+	case p.Weight > defaultH2Weight:
+		return PriorityHigh
+	case p.Weight < defaultH2Weight:
+		return PriorityLow
+	default:
+		return PriorityNormal
+	}
+}
+
+// SetH2Priority set the http2 stream priority hint of request.
+// The priority frame can't be set through net/http, so it's a best-effort
+// feature: the priority is stored (see GetH2Priority) and the Priority-Hint
+// header(high, normal or low) is set from the weight, the actual behavior
+// depends on the support of server and transport.
+func (d *Dusk) SetH2Priority(weight int, exclusive bool, dependency uint32) *Dusk {
+	if weight < minH2Weight || weight > maxH2Weight {
+		d.buildErr = ErrH2WeightInvalid
+		return d
+	}
+	d.h2Priority = &H2Priority{
+		Weight:     weight,
+		Exclusive:  exclusive,
+		Dependency: dependency,
+	}
+	return d.Set(HeaderPriorityHint, d.h2Priority.Hint())
+}
+
+// GetH2Priority get the http2 stream priority, nil if not set
+func (d *Dusk) GetH2Priority() *H2Priority {
+	return d.h2Priority
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetH2Priority(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get(HeaderPriorityHint)))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		weight int
+		hint   string
+	}{
+		{256, PriorityHigh},
+		{16, PriorityNormal},
+		{1, PriorityLow},
+	}
+	for _, tt := range tests {
+		assert := assert.New(t)
+		d := Get(ts.URL).SetH2Priority(tt.weight, true, 3)
+		_, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal(tt.hint, string(body))
+		assert.Equal(&H2Priority{
+			Weight:     tt.weight,
+			Exclusive:  true,
+			Dependency: 3,
+		}, d.GetH2Priority())
+	}
+
+	assert := assert.New(t)
+	_, _, err := Get(ts.URL).SetH2Priority(0, false, 0).Do()
+	assert.Equal(ErrH2WeightInvalid, err)
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type (
+	// ProblemDetail the problem details of RFC 7807
+	ProblemDetail struct {
+		Type     string `json:"type,omitempty"`
+		Title    string `json:"title,omitempty"`
+		Status   int    `json:"status,omitempty"`
+		Detail   string `json:"detail,omitempty"`
+		Instance string `json:"instance,omitempty"`
+	}
+	// ProblemDetailError the error of problem details response,
+	// it can be unwrapped to *ResponseError
+	ProblemDetailError struct {
+		ProblemDetail
+		err *ResponseError
+	}
+)
+
+func (e *ProblemDetailError) Error() string {
+	title := e.Title
+	if title == "" {
+		title = http.StatusText(e.err.StatusCode)
+	}
+	if e.Detail == "" {
+		return fmt.Sprintf("%s %s: %s", e.err.Method, e.err.URL, title)
+	}
+	return fmt.Sprintf("%s %s: %s, %s", e.err.Method, e.err.URL, title, e.Detail)
+}
+
+// Unwrap get the response error of problem details
+func (e *ProblemDetailError) Unwrap() error {
+	return e.err
+}
+
+// ParseProblemDetails parse the 4xx and 5xx response whose content type
+// is problem+json, the *ProblemDetailError will be returned as error.
+// The *ResponseError is returned if the body isn't valid json.
+func (d *Dusk) ParseProblemDetails() *Dusk {
+	return d.AddResponseListener(func(resp *http.Response, d *Dusk) error {
+		if resp.StatusCode < http.StatusBadRequest ||
+			d.isStreamMode() ||
+			d.isPipeMode() ||
+			!strings.Contains(strings.ToLower(resp.Header.Get(HeaderContentType)), "problem+json") {
+			return nil
+		}
+		respErr := &ResponseError{
+			StatusCode: resp.StatusCode,
+			Body:       d.Body,
+			Method:     d.GetMethod(),
+			URL:        d.Request.URL.String(),
+		}
+		e := &ProblemDetailError{
+			err: respErr,
+		}
+		if err := json.Unmarshal(d.Body, &e.ProblemDetail); err != nil {
+			return respErr
+		}
+		// 未返回 status 时使用响应状态码
+		if e.Status == 0 {
+			e.Status = resp.StatusCode
+		}
+		return e
+	}, EventTypeAfter)
+}
@@ -0,0 +1,68 @@
+package dusk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProblemDetails(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/problem":
+			w.Header().Set(HeaderContentType, MIMEApplicationProblemJSON+"; charset=utf-8")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc"}`))
+		case "/invalid":
+			w.Header().Set(HeaderContentType, MIMEApplicationProblemJSON)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`invalid`))
+		case "/json":
+			w.Header().Set(HeaderContentType, MIMEApplicationJSON)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"error"}`))
+		default:
+			w.Header().Set(HeaderContentType, MIMEApplicationProblemJSON)
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("problem details", func(t *testing.T) {
+		assert := assert.New(t)
+		_, _, err := Get(ts.URL + "/problem").ParseProblemDetails().Do()
+		pe := &ProblemDetailError{}
+		assert.True(errors.As(err, &pe))
+		assert.Equal(ProblemDetail{
+			Type:     "https://example.com/probs/out-of-credit",
+			Title:    "You do not have enough credit.",
+			Status:   http.StatusForbidden,
+			Detail:   "Your current balance is 30, but that costs 50.",
+			Instance: "/account/12345/msgs/abc",
+		}, pe.ProblemDetail)
+		assert.Equal("GET "+ts.URL+"/problem: You do not have enough credit., Your current balance is 30, but that costs 50.", pe.Error())
+
+		re := &ResponseError{}
+		assert.True(errors.As(err, &re))
+		assert.Equal(http.StatusForbidden, re.StatusCode)
+	})
+
+	t.Run("invalid problem details", func(t *testing.T) {
+		assert := assert.New(t)
+		_, _, err := Get(ts.URL + "/invalid").ParseProblemDetails().Do()
+		re := &ResponseError{}
+		assert.True(errors.As(err, &re))
+		assert.Equal("invalid", string(re.Body))
+	})
+
+	t.Run("not problem details", func(t *testing.T) {
+		assert := assert.New(t)
+		for _, path := range []string{"/json", "/"} {
+			_, _, err := Get(ts.URL + path).ParseProblemDetails().Do()
+			assert.Nil(err)
+		}
+	})
+}
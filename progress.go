@@ -0,0 +1,115 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"io"
+)
+
+type (
+	// ProgressListener progress listener, the total will be -1 if it's unknown
+	ProgressListener func(written, total int64)
+
+	progressReader struct {
+		r       io.Reader
+		written int64
+		total   int64
+		fn      ProgressListener
+	}
+
+	progressWriter struct {
+		w        io.Writer
+		written  int64
+		reported int64
+		total    int64
+		fn       ProgressListener
+	}
+)
+
+const (
+	// progressGranularity the progress listener of writer will be called
+	// every time 64KB data is written
+	progressGranularity = 64 * 1024
+)
+
+func (pr *progressReader) Read(p []byte) (n int, err error) {
+	n, err = pr.r.Read(p)
+	if n > 0 {
+		pr.written += int64(n)
+		pr.fn(pr.written, pr.total)
+	}
+	return
+}
+
+// getReaderSize get the remaining size of reader,
+// it returns -1 if the reader is not seekable.
+func getReaderSize(r io.Reader) int64 {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return -1
+	}
+	current, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	_, err = s.Seek(current, io.SeekStart)
+	if err != nil {
+		return -1
+	}
+	return end - current
+}
+
+func newProgressReader(r io.Reader, fn ProgressListener) *progressReader {
+	return &progressReader{
+		r:     r,
+		total: getReaderSize(r),
+		fn:    fn,
+	}
+}
+
+func (pw *progressWriter) Write(p []byte) (n int, err error) {
+	n, err = pw.w.Write(p)
+	if n > 0 {
+		pw.written += int64(n)
+		if pw.written-pw.reported >= progressGranularity {
+			pw.report()
+		}
+	}
+	return
+}
+
+func (pw *progressWriter) report() {
+	pw.reported = pw.written
+	pw.fn(pw.written, pw.total)
+}
+
+// finish report the progress if there is data not reported
+func (pw *progressWriter) finish() {
+	if pw.written != pw.reported {
+		pw.report()
+	}
+}
+
+func newProgressWriter(w io.Writer, total int64, fn ProgressListener) *progressWriter {
+	return &progressWriter{
+		w:     w,
+		total: total,
+		fn:    fn,
+	}
+}
@@ -0,0 +1,96 @@
+package dusk
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReaderSize(t *testing.T) {
+	assert := assert.New(t)
+	r := bytes.NewReader([]byte("abcd"))
+	assert.Equal(int64(4), getReaderSize(r))
+	r.Seek(1, io.SeekStart)
+	assert.Equal(int64(3), getReaderSize(r))
+	assert.Equal(int64(-1), getReaderSize(bytes.NewBufferString("abcd")))
+}
+
+func TestUploadProgress(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 307 重定向时需要重新发送数据
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+			return
+		}
+		buf, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(strconv.Itoa(len(buf))))
+	}))
+	defer ts.Close()
+	size := 3 * 1024 * 1024
+	data := bytes.Repeat([]byte("a"), size)
+
+	t.Run("seekable reader", func(t *testing.T) {
+		assert := assert.New(t)
+		var written, total int64
+		count := 0
+		_, body, err := Post(ts.URL).
+			Send(bytes.NewReader(data)).
+			OnUploadProgress(func(w, t int64) {
+				count++
+				written = w
+				total = t
+			}).
+			Do()
+		assert.Nil(err)
+		assert.Equal(strconv.Itoa(size), string(body))
+		assert.Equal(int64(size), written)
+		assert.Equal(int64(size), total)
+		assert.True(count > 1)
+	})
+
+	t.Run("not seekable reader", func(t *testing.T) {
+		assert := assert.New(t)
+		var written, total int64
+		_, body, err := Post(ts.URL).
+			Send(bytes.NewBuffer(data)).
+			OnUploadProgress(func(w, t int64) {
+				written = w
+				total = t
+			}).
+			Do()
+		assert.Nil(err)
+		assert.Equal(strconv.Itoa(size), string(body))
+		assert.Equal(int64(size), written)
+		assert.Equal(int64(-1), total)
+	})
+	t.Run("file", func(t *testing.T) {
+		assert := assert.New(t)
+		file := filepath.Join(t.TempDir(), "data")
+		assert.Nil(ioutil.WriteFile(file, data, 0600))
+		f, err := os.Open(file)
+		assert.Nil(err)
+		var written, total int64
+		_, body, err := Post(ts.URL + "/redirect").
+			Send(f).
+			OnUploadProgress(func(w, t int64) {
+				written = w
+				total = t
+			}).
+			Do()
+		assert.Nil(err)
+		assert.Equal(strconv.Itoa(size), string(body))
+		assert.Equal(int64(size), total)
+		assert.True(written >= int64(size))
+		// 请求完成后关闭
+		_, err = f.Seek(0, io.SeekStart)
+		assert.NotNil(err)
+	})
+}
@@ -0,0 +1,67 @@
+//go:build protobuf
+// +build protobuf
+
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// MIMEApplicationProtobuf application protobuf
+	MIMEApplicationProtobuf = "application/x-protobuf"
+)
+
+var (
+	// ErrProtoCodecType the type is not supported by protobuf codec
+	ErrProtoCodecType = errors.New("protobuf codec only supports proto.Message")
+)
+
+type protoCodec struct{}
+
+func init() {
+	RegisterCodec(MIMEApplicationProtobuf, protoCodec{})
+}
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrProtoCodecType
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return ErrProtoCodecType
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// SendProto send the protobuf message, it's marshaled to binary and
+// the content type of request will be set as application/x-protobuf if not set.
+func (d *Dusk) SendProto(m proto.Message) *Dusk {
+	return d.SendAs(MIMEApplicationProtobuf, m)
+}
+
+// ToProto unmarshal the response body into the protobuf message
+func (d *Dusk) ToProto(m proto.Message) error {
+	return proto.Unmarshal(d.Body, m)
+}
@@ -0,0 +1,67 @@
+//go:build protobuf
+// +build protobuf
+
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestProto(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		msg := &structpb.Struct{}
+		err := proto.Unmarshal(buf, msg)
+		if err != nil || r.Header.Get(HeaderContentType) != MIMEApplicationProtobuf {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		msg.Fields["id"] = structpb.NewNumberValue(1)
+		buf, _ = proto.Marshal(msg)
+		w.Header().Set(HeaderContentType, MIMEApplicationProtobuf)
+		w.Write(buf)
+	}))
+	defer ts.Close()
+
+	assert := assert.New(t)
+	msg, err := structpb.NewStruct(map[string]interface{}{
+		"name": "tree.xie",
+	})
+	assert.Nil(err)
+
+	result := &structpb.Struct{}
+	d := Post(ts.URL).SendProto(msg).Into(result)
+	resp, _, err := d.Do()
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Nil(d.DecodeErr())
+	assert.Equal(map[string]interface{}{
+		"name": "tree.xie",
+		"id":   float64(1),
+	}, result.AsMap())
+
+	result = &structpb.Struct{}
+	assert.Nil(d.ToProto(result))
+	assert.Equal("tree.xie", result.Fields["name"].GetStringValue())
+}
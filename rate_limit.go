@@ -0,0 +1,91 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"context"
+	"math"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+type (
+	// rateLimiter token bucket rate limiter of golang.org/x/time/rate,
+	// the time is got from the clock of request
+	rateLimiter struct {
+		limiter *rate.Limiter
+	}
+)
+
+// newRateLimiter create a token bucket rate limiter,
+// it's unlimited if rps isn't a positive finite number
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	limit := rate.Limit(rps)
+	// 包括 NaN 与 +Inf
+	if !(rps > 0) || math.IsInf(rps, 1) {
+		limit = rate.Inf
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		limiter: rate.NewLimiter(limit, burst),
+	}
+}
+
+// Wait wait until a token is available, the error of
+// context is returned if it is done while waiting
+func (l *rateLimiter) Wait(ctx context.Context, clock Clock) error {
+	// 使用 ReserveN 而非 limiter.Wait，时间由 clock 获取，
+	// 而且 context 的 deadline 不足时也等待至超时，返回 context 的出错
+	r := l.limiter.ReserveN(clock.Now(), 1)
+	wait := r.DelayFrom(clock.Now())
+	if wait <= 0 {
+		return nil
+	}
+	err := clock.Sleep(ctx, wait)
+	if err != nil {
+		// 归还未使用的 token
+		r.CancelAt(clock.Now())
+	}
+	return err
+}
+
+// setRateLimiter set the rate limiter of request,
+// the listener is added only once
+func (d *Dusk) setRateLimiter(l *rateLimiter) {
+	if d.rateLimiter == nil {
+		d.AddRequestListener(func(req *http.Request, d *Dusk) error {
+			return d.rateLimiter.Wait(req.Context(), d.getClock())
+		}, EventTypeBefore)
+	}
+	d.rateLimiter = l
+}
+
+// RateLimit limit the request by token bucket of rps and burst,
+// it overrides the rate limit of instance, rps <= 0 means unlimited.
+func (d *Dusk) RateLimit(rps float64, burst int) *Dusk {
+	d.setRateLimiter(newRateLimiter(rps, burst))
+	return d
+}
+
+// SetRateLimit limit all requests of instance by token bucket of rps and burst,
+// the request waits until a token is available or its context is done.
+// The rps <= 0 means unlimited.
+func (ins *Instance) SetRateLimit(rps float64, burst int) *Instance {
+	ins.rateLimiter = newRateLimiter(rps, burst)
+	return ins
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sleepClock the clock which moves forward when sleeping
+type sleepClock struct {
+	stepClock
+	sleeps []time.Duration
+}
+
+func (c *sleepClock) Sleep(_ context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func TestRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	t.Run("limit instance", func(t *testing.T) {
+		assert := assert.New(t)
+		ins := NewInstance().SetRateLimit(10, 10)
+		start := time.Now()
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, err := ins.Get(ts.URL).Do()
+				assert.Nil(err)
+			}()
+		}
+		wg.Wait()
+		assert.True(time.Since(start) >= time.Second)
+	})
+
+	t.Run("override and cancel", func(t *testing.T) {
+		assert := assert.New(t)
+		// 每1000秒只有一个 token
+		ins := NewInstance().SetRateLimit(0.001, 1)
+		_, _, err := ins.Get(ts.URL).Do()
+		assert.Nil(err)
+
+		// 单独设置的限制优先
+		start := time.Now()
+		_, _, err = ins.Get(ts.URL).RateLimit(100, 1).Do()
+		assert.Nil(err)
+		assert.True(time.Since(start) < time.Second)
+
+		_, _, err = ins.Get(ts.URL).Timeout(10 * time.Millisecond).Do()
+		assert.True(errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		assert := assert.New(t)
+		for _, rps := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+			l := newRateLimiter(rps, 1)
+			now := time.Now()
+			for i := 0; i < 3; i++ {
+				assert.Equal(time.Duration(0), l.limiter.ReserveN(now, 1).DelayFrom(now))
+			}
+		}
+
+		// 单独设置不限制时覆盖 instance 的限制
+		ins := NewInstance().SetRateLimit(0.001, 1)
+		_, _, err := ins.Get(ts.URL).Do()
+		assert.Nil(err)
+		start := time.Now()
+		_, _, err = ins.Get(ts.URL).RateLimit(0, 0).Do()
+		assert.Nil(err)
+		assert.True(time.Since(start) < time.Second)
+	})
+
+	t.Run("clock", func(t *testing.T) {
+		assert := assert.New(t)
+		clock := &sleepClock{
+			stepClock: stepClock{
+				now: time.Now(),
+			},
+		}
+		l := newRateLimiter(2, 1)
+		for i := 0; i < 3; i++ {
+			assert.Nil(l.Wait(context.Background(), clock))
+		}
+		// 时间由 clock 获取，每个 token 需等待 500ms
+		assert.Equal([]time.Duration{
+			500 * time.Millisecond,
+			500 * time.Millisecond,
+		}, clock.sleeps)
+	})
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import "net/http"
+
+const (
+	// redactedValue the value of redacted header
+	redactedValue = "***"
+)
+
+var (
+	// DefaultSensitiveHeaders the headers redacted by default when the
+	// headers are dumped for debugging, it can be extended by users
+	DefaultSensitiveHeaders = []string{
+		"Authorization",
+		"Cookie",
+		"X-API-Key",
+		"X-Auth-Token",
+	}
+)
+
+// redactHeader get a copy of header which values of keys are redacted
+func redactHeader(header http.Header, keys ...string) http.Header {
+	header = header.Clone()
+	for _, key := range keys {
+		values := header.Values(key)
+		for i := range values {
+			values[i] = redactedValue
+		}
+	}
+	return header
+}
+
+// RedactHeader add the header to redact when the headers are dumped
+// for debugging(ToCurl, ToHAR and logging listeners)
+func (d *Dusk) RedactHeader(header string) *Dusk {
+	d.redactedHeaders = append(d.redactedHeaders, header)
+	return d
+}
+
+// GetRedactedHeaders get the headers added by RedactHeader
+func (d *Dusk) GetRedactedHeaders() []string {
+	return d.redactedHeaders
+}
+
+// RedactHeader add the header to redact for all requests of instance
+func (ins *Instance) RedactHeader(header string) *Instance {
+	ins.redactedHeaders = append(ins.redactedHeaders, header)
+	return ins
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"net/http"
+)
+
+const (
+	defaultMaxRedirects = 10
+)
+
+type (
+	// RedirectPolicy the policy of redirect, it's the same as
+	// the CheckRedirect of http.Client
+	RedirectPolicy func(req *http.Request, via []*http.Request) error
+)
+
+// NoFollowRedirects the policy which doesn't follow redirect,
+// the 3xx response will be returned without error
+func NoFollowRedirects(_ *http.Request, _ []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// MaxRedirects create the policy which follows redirect up to n times,
+// ErrTooManyRedirects will be returned if redirects more than n
+func MaxRedirects(n int) RedirectPolicy {
+	return func(_ *http.Request, via []*http.Request) error {
+		if len(via) > n {
+			return ErrTooManyRedirects
+		}
+		return nil
+	}
+}
+
+// RedirectWithHeaders the policy which copies all headers of the original
+// request to the redirected request(at most 10 redirects), the sensitive
+// headers(e.g. Authorization) are also sent to the other host,
+// so it should only be used for the trusted hosts.
+func RedirectWithHeaders(req *http.Request, via []*http.Request) error {
+	if len(via) > defaultMaxRedirects {
+		return ErrTooManyRedirects
+	}
+	for key, values := range via[0].Header {
+		req.Header[key] = append([]string{}, values...)
+	}
+	return nil
+}
+
+// SetRedirectPolicy set the redirect policy of request,
+// it's set to the cloned client, so the shared client won't be modified.
+func (d *Dusk) SetRedirectPolicy(fn RedirectPolicy) *Dusk {
+	d.redirectPolicy = fn
+	return d
+}
+
+// SetRedirectPolicy set the redirect policy for all requests of instance
+func (ins *Instance) SetRedirectPolicy(fn RedirectPolicy) *Instance {
+	ins.redirectPolicy = fn
+	return ins
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	var localhostURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect2":
+			http.Redirect(w, r, "/redirect", http.StatusFound)
+		case "/redirect":
+			http.Redirect(w, r, "/target", http.StatusFound)
+		case "/redirect-other-host":
+			http.Redirect(w, r, localhostURL+"/target", http.StatusFound)
+		default:
+			w.Write([]byte(r.Header.Get("Authorization")))
+		}
+	}))
+	defer ts.Close()
+	localhostURL = strings.Replace(ts.URL, "127.0.0.1", "localhost", 1)
+
+	t.Run("no follow redirects", func(t *testing.T) {
+		assert := assert.New(t)
+		resp, _, err := Get(ts.URL + "/redirect").
+			SetRedirectPolicy(NoFollowRedirects).
+			Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusFound, resp.StatusCode)
+		assert.Equal("/target", resp.Header.Get("Location"))
+		assert.Nil(http.DefaultClient.CheckRedirect)
+	})
+
+	t.Run("max redirects", func(t *testing.T) {
+		assert := assert.New(t)
+		_, _, err := Get(ts.URL + "/redirect2").
+			SetRedirectPolicy(MaxRedirects(1)).
+			Do()
+		assert.True(errors.Is(err, ErrTooManyRedirects))
+
+		d := Get(ts.URL + "/redirect2").
+			SetRedirectPolicy(MaxRedirects(2))
+		resp, _, err := d.Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal(2, len(d.GetRedirectHistory()))
+	})
+
+	t.Run("redirect with headers", func(t *testing.T) {
+		assert := assert.New(t)
+		// 默认跨 host 重定向不发送 Authorization
+		_, body, err := Get(ts.URL+"/redirect-other-host").
+			Set("Authorization", "Bearer abcd").
+			Do()
+		assert.Nil(err)
+		assert.Empty(body)
+
+		_, body, err = Get(ts.URL+"/redirect-other-host").
+			Set("Authorization", "Bearer abcd").
+			SetRedirectPolicy(RedirectWithHeaders).
+			Do()
+		assert.Nil(err)
+		assert.Equal("Bearer abcd", string(body))
+	})
+
+	t.Run("instance", func(t *testing.T) {
+		assert := assert.New(t)
+		ins := NewInstance().SetRedirectPolicy(NoFollowRedirects)
+		resp, _, err := ins.Clone().Get(ts.URL + "/redirect").Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusFound, resp.StatusCode)
+
+		// 其它实例不受影响
+		resp, _, err = NewInstance().Get(ts.URL + "/redirect").Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,202 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	// RetryChecker check whether the error should be retried
+	RetryChecker func(error) bool
+
+	retryTransport struct {
+		base        http.RoundTripper
+		maxAttempts int
+		fn          RetryChecker
+		// attemptTimeout 每次尝试的超时
+		attemptTimeout time.Duration
+	}
+	// cancelReadCloser cancel the context of attempt when body is closed
+	cancelReadCloser struct {
+		io.ReadCloser
+		cancel context.CancelFunc
+	}
+)
+
+// Close close the body and cancel the context
+func (c *cancelReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// isRetryableError the default retry checker,
+// the error except canceled and deadline exceeded will be retried.
+func isRetryableError(err error) bool {
+	return err != nil &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// NewRetryTransport create a transport which retries the request
+// up to max attempts when the fn returns true,
+// the request can only be retried when its body is rewindable
+// (GetBody is set or it has no body).
+func NewRetryTransport(base http.RoundTripper, maxAttempts int, fn RetryChecker) http.RoundTripper {
+	return newRetryTransport(base, maxAttempts, fn, 0)
+}
+
+func newRetryTransport(base http.RoundTripper, maxAttempts int, fn RetryChecker, attemptTimeout time.Duration) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if fn == nil {
+		fn = isRetryableError
+	}
+	return &retryTransport{
+		base:           base,
+		maxAttempts:    maxAttempts,
+		fn:             fn,
+		attemptTimeout: attemptTimeout,
+	}
+}
+
+func isRewindable(req *http.Request) bool {
+	// 如果 body 的长度未知，http.NewRequest 的 content length 也为0，因此不能以此判断
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// RoundTrip do the request with retry
+func (t *retryTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	rewindable := isRewindable(req)
+	for i := 1; ; i++ {
+		r := req
+		if i > 1 {
+			// 记录重试次数
+			if d := FromContext(req.Context()); d != nil {
+				d.retries++
+			}
+		}
+		// 重试的请求需要重新获取 body
+		if i > 1 && req.GetBody != nil {
+			body, e := req.GetBody()
+			if e != nil {
+				return nil, e
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		ctx := r.Context()
+		var cancel context.CancelFunc
+		if t.attemptTimeout > 0 {
+			// 总超时由请求的 context 控制，因此实际的超时为两者中较小的值
+			ctx, cancel = context.WithTimeout(ctx, t.attemptTimeout)
+			r = r.WithContext(ctx)
+		}
+		resp, err = t.base.RoundTrip(r)
+		if cancel != nil {
+			// 成功时 body 仍需读取，在关闭时才 cancel
+			if err == nil {
+				resp.Body = &cancelReadCloser{
+					ReadCloser: resp.Body,
+					cancel:     cancel,
+				}
+			} else {
+				cancel()
+			}
+		}
+		// 单次尝试超时而总超时未到，可继续重试
+		attemptTimedOut := err != nil && ctx.Err() != nil
+		if err == nil ||
+			!rewindable ||
+			i >= t.maxAttempts ||
+			req.Context().Err() != nil ||
+			!(attemptTimedOut || t.fn(err)) {
+			return
+		}
+	}
+}
+
+// SetRetryTransport set the retry transport for the request,
+// the network error will be retried up to max attempts.
+// The request body of reader larger than 1MB is not buffered(except the
+// read seeker such as file, which is seeked back for retry),
+// so the request with it won't be retried unless BufferBody is set.
+func (d *Dusk) SetRetryTransport(maxAttempts int) *Dusk {
+	d.retryAttempts = maxAttempts
+	return d
+}
+
+// BufferBody buffer the whole body of reader in memory before sending,
+// so the request can be retried or redirected with body even if the
+// reader is larger than 1MB. It should only be used for the request
+// which may be sent more than once.
+func (d *Dusk) BufferBody() *Dusk {
+	d.bodyBuffered = true
+	return d
+}
+
+// bufferBody read the body of reader into memory and replace it with
+// bytes reader, the reader backed by buffer is kept as it's rewindable.
+func (d *Dusk) bufferBody() error {
+	if !d.bodyBuffered {
+		return nil
+	}
+	v, ok := d.data.(io.Reader)
+	if !ok {
+		return nil
+	}
+	switch v.(type) {
+	case *bytes.Buffer, *bytes.Reader, *strings.Reader:
+		return nil
+	}
+	buf, err := ioutil.ReadAll(v)
+	if closer, ok := v.(io.Closer); ok {
+		closer.Close()
+	}
+	if err != nil {
+		return err
+	}
+	d.data = bytes.NewReader(buf)
+	return nil
+}
+
+// SetAttemptTimeout set the timeout of every attempt, the timeout of
+// attempt is the min of it and the remaining total timeout, so a slow
+// attempt won't consume the whole budget of retries.
+// The reading of response body is included in the attempt.
+func (d *Dusk) SetAttemptTimeout(timeout time.Duration) *Dusk {
+	d.attemptTimeout = timeout
+	return d
+}
+
+// SetTotalTimeout set the timeout of the whole request including all
+// attempts of retry, it's the same as Timeout.
+func (d *Dusk) SetTotalTimeout(timeout time.Duration) *Dusk {
+	return d.Timeout(timeout)
+}
+
+// GetRetries get the retry count of request(not including the first attempt)
+func (d *Dusk) GetRetries() int {
+	return d.retries
+}
@@ -0,0 +1,191 @@
+package dusk
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	assert := assert.New(t)
+	assert.False(isRetryableError(nil))
+	assert.False(isRetryableError(context.Canceled))
+	assert.False(isRetryableError(context.DeadlineExceeded))
+	assert.True(isRetryableError(errors.New("abcd")))
+}
+
+func TestRetryTransport(t *testing.T) {
+	e := errors.New("connection reset")
+	newTransport := func(failures int, bodies *[]string) http.RoundTripper {
+		count := 0
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			count++
+			if req.Body != nil {
+				buf, _ := ioutil.ReadAll(req.Body)
+				*bodies = append(*bodies, string(buf))
+			}
+			if count <= failures {
+				return nil, e
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(strings.NewReader("ok")),
+				Request:    req,
+			}, nil
+		})
+	}
+
+	t.Run("retry success", func(t *testing.T) {
+		assert := assert.New(t)
+		bodies := make([]string, 0)
+		d := Post("http://aslant.site/").
+			SetClient(&http.Client{
+				Transport: newTransport(2, &bodies),
+			}).
+			Send(map[string]string{
+				"name": "tree.xie",
+			}).
+			SetRetryTransport(3)
+		_, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal("ok", string(body))
+		assert.Equal(2, d.GetRetries())
+		assert.Equal([]string{
+			`{"name":"tree.xie"}`,
+			`{"name":"tree.xie"}`,
+			`{"name":"tree.xie"}`,
+		}, bodies)
+	})
+
+	t.Run("retry fail", func(t *testing.T) {
+		assert := assert.New(t)
+		bodies := make([]string, 0)
+		_, _, err := Get("http://aslant.site/").
+			SetClient(&http.Client{
+				Transport: newTransport(3, &bodies),
+			}).
+			SetRetryTransport(3).
+			Do()
+		assert.True(errors.Is(err, e))
+	})
+
+	t.Run("retry for small reader", func(t *testing.T) {
+		assert := assert.New(t)
+		bodies := make([]string, 0)
+		_, _, err := Post("http://aslant.site/").
+			SetClient(&http.Client{
+				Transport: newTransport(1, &bodies),
+			}).
+			Send(ioutil.NopCloser(strings.NewReader("abcd"))).
+			SetRetryTransport(3).
+			Do()
+		assert.Nil(err)
+		assert.Equal([]string{"abcd", "abcd"}, bodies)
+	})
+
+	t.Run("not retry for not rewindable body", func(t *testing.T) {
+		assert := assert.New(t)
+		bodies := make([]string, 0)
+		data := strings.Repeat("a", maxRewindableBodySize+1)
+		_, _, err := Post("http://aslant.site/").
+			SetClient(&http.Client{
+				Transport: newTransport(1, &bodies),
+			}).
+			Send(ioutil.NopCloser(strings.NewReader(data))).
+			SetRetryTransport(3).
+			Do()
+		assert.True(errors.Is(err, e))
+		assert.Equal([]string{data}, bodies)
+	})
+
+	t.Run("retry for buffered body", func(t *testing.T) {
+		assert := assert.New(t)
+		bodies := make([]string, 0)
+		data := strings.Repeat("a", maxRewindableBodySize+1)
+		d := Post("http://aslant.site/").
+			SetClient(&http.Client{
+				Transport: newTransport(2, &bodies),
+			}).
+			Send(ioutil.NopCloser(strings.NewReader(data))).
+			BufferBody().
+			SetRetryTransport(3)
+		_, _, err := d.Do()
+		assert.Nil(err)
+		assert.Equal(2, d.GetRetries())
+		assert.Equal([]string{data, data, data}, bodies)
+	})
+
+	t.Run("not retry by checker", func(t *testing.T) {
+		assert := assert.New(t)
+		bodies := make([]string, 0)
+		client := &http.Client{
+			Transport: NewRetryTransport(newTransport(1, &bodies), 3, func(_ error) bool {
+				return false
+			}),
+		}
+		_, _, err := Get("http://aslant.site/").
+			SetClient(client).
+			Do()
+		assert.True(errors.Is(err, e))
+	})
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 首次请求响应缓慢
+		if atomic.AddInt32(&count, 1) == 1 || r.URL.Path == "/slow" {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	t.Run("retry slow attempt", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&count, 0)
+		d := Get(ts.URL).
+			SetRetryTransport(3).
+			SetAttemptTimeout(50 * time.Millisecond).
+			SetTotalTimeout(500 * time.Millisecond)
+		_, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal("ok", string(body))
+		assert.Equal(1, d.GetRetries())
+	})
+
+	t.Run("without retry", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&count, 0)
+		_, _, err := Get(ts.URL).
+			SetAttemptTimeout(50 * time.Millisecond).
+			Do()
+		assert.True(errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("limited by total timeout", func(t *testing.T) {
+		assert := assert.New(t)
+		start := time.Now()
+		d := Get(ts.URL + "/slow").
+			SetRetryTransport(3).
+			SetAttemptTimeout(500 * time.Millisecond).
+			SetTotalTimeout(100 * time.Millisecond)
+		_, _, err := d.Do()
+		assert.True(errors.Is(err, context.DeadlineExceeded))
+		assert.True(time.Since(start) < 400*time.Millisecond)
+		assert.Equal(0, d.GetRetries())
+	})
+}
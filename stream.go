@@ -0,0 +1,86 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+var (
+	// ErrStreamDecode the response can't be decoded in stream mode
+	ErrStreamDecode = errors.New("content decoding is not supported in stream mode, only gzip can be streamed")
+)
+
+type streamReader struct {
+	io.Reader
+	closers []io.Closer
+	d       *Dusk
+}
+
+// Close close the response body and cancel the timeout context
+func (sr *streamReader) Close() (err error) {
+	for _, c := range sr.closers {
+		e := c.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	sr.d.cancelContext()
+	return
+}
+
+// Stream don't read the response body, it should be read by the reader
+// of GetResponseReader and the reader must be closed by the caller.
+// The Body of dusk will be nil, the response after listeners are still called
+// before the body is read, so they can't get the data of body.
+// The timeout is still working until the reader is closed.
+// It will be ignored in pipe mode.
+func (d *Dusk) Stream() *Dusk {
+	d.stream = true
+	return d
+}
+
+// GetResponseReader get the reader of response body in stream mode,
+// it will be nil if the request fails or not in stream mode.
+func (d *Dusk) GetResponseReader() io.ReadCloser {
+	return d.responseReader
+}
+
+func (d *Dusk) isStreamMode() bool {
+	return d.stream && !d.isPipeMode()
+}
+
+// newStreamReader create the reader of response body for stream mode
+func (d *Dusk) newStreamReader(resp *http.Response) error {
+	sr := &streamReader{
+		Reader: resp.Body,
+		d:      d,
+	}
+	// 如果手工设置了 Accept-Encoding，http transport 不会自动解压 gzip，
+	// gzip 可以流式解压，因此读取时解压
+	if resp.Header.Get(HeaderContentEncoding) == GzipEncoding {
+		gr, err := newGzipStreamReader(resp)
+		if err != nil {
+			return err
+		}
+		sr.Reader = gr
+		sr.closers = append(sr.closers, gr)
+	}
+	sr.closers = append(sr.closers, resp.Body)
+	d.responseReader = sr
+	return nil
+}
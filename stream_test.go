@@ -0,0 +1,121 @@
+// Copyright 2019 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dusk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream(t *testing.T) {
+	// 4MB 数据
+	data := bytes.Repeat([]byte("abcdefgh"), 512*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set(HeaderContentEncoding, GzipEncoding)
+			gw := gzip.NewWriter(w)
+			gw.Write(data)
+			gw.Close()
+		case "/br":
+			w.Header().Set(HeaderContentEncoding, BrEncoding)
+			w.Write(data)
+		default:
+			w.Write(data)
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("read incrementally", func(t *testing.T) {
+		assert := assert.New(t)
+		afterCalled := false
+		d := Get(ts.URL).
+			Timeout(5 * time.Second).
+			Stream()
+		d.AddResponseListener(func(_ *http.Response, d *Dusk) error {
+			afterCalled = true
+			assert.Nil(d.Body)
+			return nil
+		}, EventTypeAfter)
+		resp, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Nil(body)
+		assert.True(afterCalled)
+
+		r := d.GetResponseReader()
+		assert.NotNil(r)
+		defer r.Close()
+		result := new(bytes.Buffer)
+		buf := make([]byte, 32*1024)
+		reads := 0
+		for {
+			n, err := r.Read(buf)
+			result.Write(buf[:n])
+			reads++
+			if err == io.EOF {
+				break
+			}
+			assert.Nil(err)
+		}
+		assert.True(reads > 1)
+		assert.Equal(data, result.Bytes())
+	})
+
+	t.Run("stream gzip response", func(t *testing.T) {
+		assert := assert.New(t)
+		d := Get(ts.URL+"/gzip").
+			Set(HeaderAcceptEncoding, GzipEncoding).
+			Stream()
+		resp, _, err := d.Do()
+		assert.Nil(err)
+		assert.Empty(resp.Header.Get(HeaderContentEncoding))
+		r := d.GetResponseReader()
+		defer r.Close()
+		result := new(bytes.Buffer)
+		_, err = io.Copy(result, r)
+		assert.Nil(err)
+		assert.Equal(data, result.Bytes())
+	})
+
+	t.Run("decoder is not supported", func(t *testing.T) {
+		assert := assert.New(t)
+		d := Get(ts.URL + "/br").
+			Br().
+			Stream()
+		_, _, err := d.Do()
+		assert.Equal(ErrStreamDecode, err)
+		assert.Nil(d.GetResponseReader())
+	})
+
+	t.Run("ignored in pipe mode", func(t *testing.T) {
+		assert := assert.New(t)
+		b := new(bytes.Buffer)
+		d := Get(ts.URL).
+			Stream().
+			Pipe(b)
+		_, _, err := d.Do()
+		assert.Nil(err)
+		assert.Nil(d.GetResponseReader())
+		assert.Equal(data, b.Bytes())
+	})
+}
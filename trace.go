@@ -16,7 +16,13 @@ package dusk
 
 import (
 	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
 	"net/http/httptrace"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -27,25 +33,36 @@ type (
 		DNSLookup        time.Duration `json:"dnsLookup,omitempty"`
 		TCPConnection    time.Duration `json:"tcpConnection,omitempty"`
 		TLSHandshake     time.Duration `json:"tlsHandshake,omitempty"`
+		RequestWrite     time.Duration `json:"requestWrite,omitempty"`
 		ServerProcessing time.Duration `json:"serverProcessing,omitempty"`
 		ContentTransfer  time.Duration `json:"contentTransfer,omitempty"`
 		Total            time.Duration `json:"total,omitempty"`
 	}
+	// RedirectInfo redirect info
+	RedirectInfo struct {
+		From       string `json:"from,omitempty"`
+		To         string `json:"to,omitempty"`
+		StatusCode int    `json:"statusCode,omitempty"`
+	}
 	// HTTPTrace http trace
 	HTTPTrace struct {
 		// 因为timeout的设置有可能导致 trace 读写并存，因此需要锁
 		sync.RWMutex
-		Host           string        `json:"host,omitempty"`
-		Addrs          []string      `json:"addrs,omitempty"`
-		Network        string        `json:"network,omitempty"`
-		Addr           string        `json:"addr,omitempty"`
-		Reused         bool          `json:"reused,omitempty"`
-		WasIdle        bool          `json:"wasIdle,omitempty"`
-		IdleTime       time.Duration `json:"idleTime,omitempty"`
-		Protocol       string        `json:"protocol,omitempty"`
-		TLSVersion     string        `json:"tlsVersion,omitempty"`
-		TLSResume      bool          `json:"tlsResume,omitempty"`
-		TLSCipherSuite string        `json:"tlsCipherSuite,omitempty"`
+		Method         string         `json:"method,omitempty"`
+		URL            string         `json:"url,omitempty"`
+		Host           string         `json:"host,omitempty"`
+		Addrs          []string       `json:"addrs,omitempty"`
+		Network        string         `json:"network,omitempty"`
+		Addr           string         `json:"addr,omitempty"`
+		LocalAddr      string         `json:"localAddr,omitempty"`
+		Reused         bool           `json:"reused,omitempty"`
+		WasIdle        bool           `json:"wasIdle,omitempty"`
+		IdleTime       time.Duration  `json:"idleTime,omitempty"`
+		Protocol       string         `json:"protocol,omitempty"`
+		TLSVersion     string         `json:"tlsVersion,omitempty"`
+		TLSResume      bool           `json:"tlsResume,omitempty"`
+		TLSCipherSuite string         `json:"tlsCipherSuite,omitempty"`
+		Redirects      []RedirectInfo `json:"redirects,omitempty"`
 
 		Start                time.Time `json:"start,omitempty"`
 		DNSStart             time.Time `json:"dnsStart,omitempty"`
@@ -53,10 +70,14 @@ type (
 		ConnectStart         time.Time `json:"connectStart,omitempty"`
 		ConnectDone          time.Time `json:"connectDone,omitempty"`
 		GotConnect           time.Time `json:"gotConnect,omitempty"`
+		WroteHeaderTime      time.Time `json:"wroteHeaderTime,omitempty"`
+		WroteRequest         time.Time `json:"wroteRequest,omitempty"`
 		GotFirstResponseByte time.Time `json:"gotFirstResponseByte,omitempty"`
 		TLSHandshakeStart    time.Time `json:"tlsHandshakeStart,omitempty"`
 		TLSHandshakeDone     time.Time `json:"tlsHandshakeDone,omitempty"`
 		Done                 time.Time `json:"done,omitempty"`
+
+		clock Clock
 	}
 )
 
@@ -67,6 +88,8 @@ var (
 
 const (
 	unknown = "unknown"
+	// defaultRenderWidth the default width of bars for render
+	defaultRenderWidth = 50
 )
 
 func init() {
@@ -119,11 +142,84 @@ func convertCipherSuite(cipherSuite uint16) string {
 	return v
 }
 
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
+
+// formatMilliseconds format the duration as milliseconds without trailing zeros
+func formatMilliseconds(d time.Duration) string {
+	v := strconv.FormatFloat(toMilliseconds(d), 'f', 2, 64)
+	v = strings.TrimRight(strings.TrimRight(v, "0"), ".")
+	return v + "ms"
+}
+
+// toMilliseconds convert the duration to milliseconds
+func toMilliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
+
+// String get the readable stats in one line, the phases which are zero
+// (such as dns, tcp and tls of reused connection) are omitted,
+// e.g. dns=2ms tcp=10ms tls=35ms server=120ms transfer=5ms total=172ms
+func (stats *HTTPTimelineStats) String() string {
+	phases := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"dns", stats.DNSLookup},
+		{"tcp", stats.TCPConnection},
+		{"tls", stats.TLSHandshake},
+		{"write", stats.RequestWrite},
+		{"server", stats.ServerProcessing},
+		{"transfer", stats.ContentTransfer},
+	}
+	arr := make([]string, 0, len(phases)+1)
+	for _, phase := range phases {
+		if phase.d == 0 {
+			continue
+		}
+		arr = append(arr, phase.name+"="+formatMilliseconds(phase.d))
+	}
+	// total 总是输出
+	arr = append(arr, "total="+formatMilliseconds(stats.Total))
+	return strings.Join(arr, " ")
+}
+
+// MarshalJSON marshal the stats to json, the durations are
+// converted to milliseconds (float) instead of nanoseconds
+func (stats HTTPTimelineStats) MarshalJSON() ([]byte, error) {
+	type statsMS struct {
+		DNSLookup        float64 `json:"dnsLookup,omitempty"`
+		TCPConnection    float64 `json:"tcpConnection,omitempty"`
+		TLSHandshake     float64 `json:"tlsHandshake,omitempty"`
+		RequestWrite     float64 `json:"requestWrite,omitempty"`
+		ServerProcessing float64 `json:"serverProcessing,omitempty"`
+		ContentTransfer  float64 `json:"contentTransfer,omitempty"`
+		Total            float64 `json:"total,omitempty"`
+	}
+	return json.Marshal(&statsMS{
+		DNSLookup:        toMilliseconds(stats.DNSLookup),
+		TCPConnection:    toMilliseconds(stats.TCPConnection),
+		TLSHandshake:     toMilliseconds(stats.TLSHandshake),
+		RequestWrite:     toMilliseconds(stats.RequestWrite),
+		ServerProcessing: toMilliseconds(stats.ServerProcessing),
+		ContentTransfer:  toMilliseconds(stats.ContentTransfer),
+		Total:            toMilliseconds(stats.Total),
+	})
+}
+
 // Finish http trace finish
 func (ht *HTTPTrace) Finish() {
 	ht.Lock()
 	defer ht.Unlock()
-	ht.Done = time.Now()
+	ht.Done = ht.now()
+}
+
+// addRedirect add redirect info to trace
+func (ht *HTTPTrace) addRedirect(info RedirectInfo) {
+	ht.Lock()
+	defer ht.Unlock()
+	ht.Redirects = append(ht.Redirects, info)
 }
 
 // Stats get the stats of time line
@@ -141,11 +237,14 @@ func (ht *HTTPTrace) Stats() (stats *HTTPTimelineStats) {
 		stats.TLSHandshake = ht.TLSHandshakeDone.Sub(ht.TLSHandshakeStart)
 	}
 
+	if !ht.GotConnect.IsZero() && !ht.WroteRequest.IsZero() {
+		stats.RequestWrite = ht.WroteRequest.Sub(ht.GotConnect)
+	}
 	if !ht.GotConnect.IsZero() && !ht.GotFirstResponseByte.IsZero() {
 		stats.ServerProcessing = ht.GotFirstResponseByte.Sub(ht.GotConnect)
 	}
 	if ht.Done.IsZero() {
-		ht.Done = time.Now()
+		ht.Done = ht.now()
 	}
 	if !ht.GotFirstResponseByte.IsZero() {
 		stats.ContentTransfer = ht.Done.Sub(ht.GotFirstResponseByte)
@@ -154,17 +253,101 @@ func (ht *HTTPTrace) Stats() (stats *HTTPTimelineStats) {
 	return
 }
 
+// Render render the trace as ascii waterfall, one bar per phase which is
+// proportional to its duration and the duration is on the right.
+// The dns, connect and tls phases of reused connection are shown as (reused).
+// The width is the columns of bars, 50 is used if it's not greater than 0.
+func (ht *HTTPTrace) Render(w io.Writer, width int) error {
+	if width <= 0 {
+		width = defaultRenderWidth
+	}
+	stats := ht.Stats()
+	ht.RLock()
+	defer ht.RUnlock()
+	// 等待响应从请求发送完成开始计算，避免与发送请求重叠
+	waitingStart := ht.WroteRequest
+	if waitingStart.IsZero() {
+		waitingStart = ht.GotConnect
+	}
+	phases := []struct {
+		name   string
+		start  time.Time
+		end    time.Time
+		reused bool
+	}{
+		{"DNS", ht.DNSStart, ht.DNSDone, true},
+		{"Connect", ht.ConnectStart, ht.ConnectDone, true},
+		{"TLS", ht.TLSHandshakeStart, ht.TLSHandshakeDone, true},
+		{"Request", ht.GotConnect, ht.WroteRequest, false},
+		{"Waiting", waitingStart, ht.GotFirstResponseByte, false},
+		{"Download", ht.GotFirstResponseByte, ht.Done, false},
+	}
+	total := stats.Total
+	// 根据时间转换为对应的列
+	column := func(t time.Time) int {
+		if total <= 0 {
+			return 0
+		}
+		v := int(math.Round(float64(t.Sub(ht.Start)) / float64(total) * float64(width)))
+		if v < 0 {
+			return 0
+		}
+		if v > width {
+			return width
+		}
+		return v
+	}
+	sb := new(strings.Builder)
+	for _, phase := range phases {
+		if phase.start.IsZero() || phase.end.IsZero() {
+			if phase.reused && ht.Reused {
+				fmt.Fprintf(sb, "%-10s|%s| %10s\n", phase.name, strings.Repeat(" ", width), "(reused)")
+			}
+			continue
+		}
+		start := column(phase.start)
+		end := column(phase.end)
+		// 有耗时的阶段至少显示一列
+		if end <= start && phase.end.After(phase.start) {
+			if start >= width {
+				start = width - 1
+			}
+			end = start + 1
+		}
+		bar := strings.Repeat(" ", start) +
+			strings.Repeat("=", end-start) +
+			strings.Repeat(" ", width-end)
+		fmt.Fprintf(sb, "%-10s|%s| %10s\n", phase.name, bar, formatDuration(phase.end.Sub(phase.start)))
+	}
+	fmt.Fprintf(sb, "%-10s %s  %10s\n", "Total", strings.Repeat(" ", width), formatDuration(total))
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// now get the current time from the clock of trace
+func (ht *HTTPTrace) now() time.Time {
+	if ht.clock == nil {
+		return time.Now()
+	}
+	return ht.clock.Now()
+}
+
 // NewClientTrace http client trace
 func NewClientTrace() (trace *httptrace.ClientTrace, ht *HTTPTrace) {
+	return newClientTrace(GetClock())
+}
+
+func newClientTrace(clock Clock) (trace *httptrace.ClientTrace, ht *HTTPTrace) {
 	ht = &HTTPTrace{
-		Start: time.Now(),
+		Start: clock.Now(),
+		clock: clock,
 	}
 	trace = &httptrace.ClientTrace{
 		DNSStart: func(info httptrace.DNSStartInfo) {
 			ht.Lock()
 			defer ht.Unlock()
 			ht.Host = info.Host
-			ht.DNSStart = time.Now()
+			ht.DNSStart = ht.now()
 		},
 		DNSDone: func(info httptrace.DNSDoneInfo) {
 			ht.Lock()
@@ -173,19 +356,19 @@ func NewClientTrace() (trace *httptrace.ClientTrace, ht *HTTPTrace) {
 			for index, addr := range info.Addrs {
 				ht.Addrs[index] = addr.String()
 			}
-			ht.DNSDone = time.Now()
+			ht.DNSDone = ht.now()
 		},
 		ConnectStart: func(network, addr string) {
 			ht.Lock()
 			defer ht.Unlock()
 			ht.Network = network
 			ht.Addr = addr
-			ht.ConnectStart = time.Now()
+			ht.ConnectStart = ht.now()
 		},
 		ConnectDone: func(_, _ string, _ error) {
 			ht.Lock()
 			defer ht.Unlock()
-			ht.ConnectDone = time.Now()
+			ht.ConnectDone = ht.now()
 		},
 		GotConn: func(info httptrace.GotConnInfo) {
 			ht.Lock()
@@ -193,18 +376,31 @@ func NewClientTrace() (trace *httptrace.ClientTrace, ht *HTTPTrace) {
 			ht.Reused = info.Reused
 			ht.WasIdle = info.WasIdle
 			ht.IdleTime = info.IdleTime
+			if info.Conn != nil {
+				ht.LocalAddr = info.Conn.LocalAddr().String()
+			}
 
-			ht.GotConnect = time.Now()
+			ht.GotConnect = ht.now()
+		},
+		WroteHeaderField: func(_ string, _ []string) {
+			ht.Lock()
+			defer ht.Unlock()
+			ht.WroteHeaderTime = ht.now()
+		},
+		WroteRequest: func(_ httptrace.WroteRequestInfo) {
+			ht.Lock()
+			defer ht.Unlock()
+			ht.WroteRequest = ht.now()
 		},
 		GotFirstResponseByte: func() {
 			ht.Lock()
 			defer ht.Unlock()
-			ht.GotFirstResponseByte = time.Now()
+			ht.GotFirstResponseByte = ht.now()
 		},
 		TLSHandshakeStart: func() {
 			ht.Lock()
 			defer ht.Unlock()
-			ht.TLSHandshakeStart = time.Now()
+			ht.TLSHandshakeStart = ht.now()
 		},
 		TLSHandshakeDone: func(info tls.ConnectionState, _ error) {
 			ht.Lock()
@@ -214,7 +410,7 @@ func NewClientTrace() (trace *httptrace.ClientTrace, ht *HTTPTrace) {
 			ht.TLSCipherSuite = convertCipherSuite(info.CipherSuite)
 			ht.Protocol = info.NegotiatedProtocol
 
-			ht.TLSHandshakeDone = time.Now()
+			ht.TLSHandshakeDone = ht.now()
 		},
 	}
 	return
@@ -2,8 +2,12 @@ package dusk
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/http/httptrace"
+	"strings"
 	"testing"
 	"time"
 )
@@ -61,6 +65,18 @@ func TestTrace(t *testing.T) {
 	})
 	time.Sleep(time.Millisecond)
 
+	trace.WroteHeaderField("Host", []string{"aslant.site"})
+	if ht.WroteHeaderTime.IsZero() {
+		t.Fatalf("wrote header time should be set")
+	}
+	time.Sleep(time.Millisecond)
+
+	trace.WroteRequest(httptrace.WroteRequestInfo{})
+	if ht.WroteRequest.IsZero() {
+		t.Fatalf("wrote request time should be set")
+	}
+	time.Sleep(time.Millisecond)
+
 	trace.GotFirstResponseByte()
 	time.Sleep(time.Millisecond)
 
@@ -68,9 +84,172 @@ func TestTrace(t *testing.T) {
 	if stats.DNSLookup == 0 ||
 		stats.TCPConnection == 0 ||
 		stats.TLSHandshake == 0 ||
+		stats.RequestWrite == 0 ||
 		stats.ServerProcessing == 0 ||
 		stats.ContentTransfer == 0 ||
 		stats.Total == 0 {
 		t.Fatalf("get http stats fail")
 	}
 }
+
+func TestTraceRedirects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/a", http.StatusMovedPermanently)
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		default:
+			w.Write([]byte("done"))
+		}
+	}))
+	defer ts.Close()
+
+	d := Get(ts.URL + "/").EnableTrace()
+	_, body, err := d.Do()
+	if err != nil || string(body) != "done" {
+		t.Fatalf("redirect request fail, %v", err)
+	}
+	redirects := d.GetHTTPTrace().Redirects
+	if len(redirects) != 2 {
+		t.Fatalf("trace redirects fail")
+	}
+	if redirects[0].From != ts.URL+"/" ||
+		redirects[0].To != ts.URL+"/a" ||
+		redirects[0].StatusCode != http.StatusMovedPermanently {
+		t.Fatalf("trace the first redirect fail")
+	}
+	if redirects[1].From != ts.URL+"/a" ||
+		redirects[1].To != ts.URL+"/b" ||
+		redirects[1].StatusCode != http.StatusFound {
+		t.Fatalf("trace the second redirect fail")
+	}
+}
+
+func TestHTTPTimelineStatsString(t *testing.T) {
+	stats := &HTTPTimelineStats{
+		DNSLookup:        12 * time.Millisecond,
+		TCPConnection:    3 * time.Millisecond,
+		ServerProcessing: 1500 * time.Microsecond,
+		Total:            20 * time.Millisecond,
+	}
+	expected := "dns=12ms tcp=3ms server=1.5ms total=20ms"
+	if stats.String() != expected {
+		t.Fatalf("timeline stats to string fail, %s", stats.String())
+	}
+
+	// 复用连接，没有dns tcp tls
+	stats = &HTTPTimelineStats{
+		ServerProcessing: 120 * time.Millisecond,
+		ContentTransfer:  5 * time.Millisecond,
+		Total:            125 * time.Millisecond,
+	}
+	if stats.String() != "server=120ms transfer=5ms total=125ms" {
+		t.Fatalf("timeline stats of reused connection to string fail, %s", stats.String())
+	}
+}
+
+func TestHTTPTimelineStatsMarshalJSON(t *testing.T) {
+	stats := &HTTPTimelineStats{
+		DNSLookup:        12 * time.Millisecond,
+		ServerProcessing: 1500 * time.Microsecond,
+		Total:            20 * time.Millisecond,
+	}
+	buf, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("marshal timeline stats fail, %v", err)
+	}
+	expected := `{"dnsLookup":12,"serverProcessing":1.5,"total":20}`
+	if string(buf) != expected {
+		t.Fatalf("timeline stats to json fail, %s", string(buf))
+	}
+}
+
+func TestGetTimelineStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	d := Get(ts.URL)
+	_, _, err := d.Do()
+	if err != nil || d.GetTimelineStats() != nil {
+		t.Fatalf("timeline stats should be nil if trace is not enabled")
+	}
+
+	d = Get(ts.URL).EnableTrace()
+	_, _, err = d.Do()
+	if err != nil {
+		t.Fatalf("request fail, %v", err)
+	}
+	stats := d.GetTimelineStats()
+	if stats == nil || stats.Total == 0 {
+		t.Fatalf("get timeline stats fail")
+	}
+	if strings.Contains(stats.String(), "\n") ||
+		!strings.Contains(stats.String(), "total=") {
+		t.Fatalf("timeline stats to string fail")
+	}
+}
+
+func TestHTTPTraceRender(t *testing.T) {
+	start := time.Unix(0, 0)
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+	ht := &HTTPTrace{
+		Start:                start,
+		DNSStart:             at(0),
+		DNSDone:              at(10),
+		ConnectStart:         at(10),
+		ConnectDone:          at(30),
+		TLSHandshakeStart:    at(30),
+		TLSHandshakeDone:     at(60),
+		GotConnect:           at(60),
+		WroteRequest:         at(62),
+		GotFirstResponseByte: at(90),
+		Done:                 at(100),
+	}
+	b := new(strings.Builder)
+	err := ht.Render(b, 20)
+	if err != nil {
+		t.Fatalf("render trace fail, %v", err)
+	}
+	expected := `DNS       |==                  |    10.00ms
+Connect   |  ====              |    20.00ms
+TLS       |      ======        |    30.00ms
+Request   |            =       |     2.00ms
+Waiting   |            ======  |    28.00ms
+Download  |                  ==|    10.00ms
+Total                              100.00ms
+`
+	if b.String() != expected {
+		t.Fatalf("render trace fail, \n%s", b.String())
+	}
+
+	// 复用的连接
+	ht = &HTTPTrace{
+		Reused:               true,
+		Start:                start,
+		GotConnect:           at(0),
+		WroteRequest:         at(1),
+		GotFirstResponseByte: at(15),
+		Done:                 at(20),
+	}
+	b = new(strings.Builder)
+	err = ht.Render(b, 20)
+	if err != nil {
+		t.Fatalf("render trace fail, %v", err)
+	}
+	expected = `DNS       |                    |   (reused)
+Connect   |                    |   (reused)
+TLS       |                    |   (reused)
+Request   |=                   |     1.00ms
+Waiting   | ==============     |    14.00ms
+Download  |               =====|     5.00ms
+Total                               20.00ms
+`
+	if b.String() != expected {
+		t.Fatalf("render reused trace fail, \n%s", b.String())
+	}
+}
@@ -0,0 +1,742 @@
+package dusk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneTransport(t *testing.T) {
+	assert := assert.New(t)
+	transport := &http.Transport{}
+	client := &http.Client{
+		Transport: transport,
+	}
+	d := Get("http://aslant.site/").
+		SetClient(client).
+		Timeouts(time.Second, 2*time.Second, 3*time.Second, 0)
+	c, err := d.getRequestClient()
+	assert.Nil(err)
+	cloned := c.Transport.(*http.Transport)
+	assert.NotEqual(transport, cloned)
+	assert.NotNil(cloned.DialContext)
+	assert.Equal(2*time.Second, cloned.TLSHandshakeTimeout)
+	assert.Equal(3*time.Second, cloned.ResponseHeaderTimeout)
+	// 原有的 transport 不受影响
+	assert.Nil(transport.DialContext)
+	assert.Equal(time.Duration(0), transport.TLSHandshakeTimeout)
+	assert.Equal(client, d.GetClient())
+
+	d = Get("http://aslant.site/").
+		SetClient(&http.Client{
+			Transport: roundTripperFunc(nil),
+		}).
+		Timeouts(time.Second, 0, 0, 0)
+	_, _, err = d.Do()
+	assert.Equal(ErrTransportNotSupported, err)
+}
+
+func TestCloseClonedTransport(t *testing.T) {
+	assert := assert.New(t)
+	var mu sync.Mutex
+	conns := make(map[net.Conn]http.ConnState)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		conns[conn] = state
+	}
+	ts.Start()
+	defer ts.Close()
+
+	d := Get(ts.URL).Timeouts(time.Second, 0, 0, 0)
+	_, body, err := d.Do()
+	assert.Nil(err)
+	assert.Equal("ok", string(body))
+	assert.Nil(d.clonedTransport)
+
+	// 复制的 transport 仅用于本次请求，完成后关闭其空闲连接
+	closed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, state := range conns {
+			if state != http.StateClosed {
+				return false
+			}
+		}
+		return len(conns) == 1
+	}
+	assert.Eventually(closed, time.Second, 10*time.Millisecond)
+}
+
+func TestTimeouts(t *testing.T) {
+	isTimeout := func(err error) bool {
+		ue, ok := err.(*url.Error)
+		return ok && ue.Timeout()
+	}
+
+	t.Run("tls handshake timeout", func(t *testing.T) {
+		assert := assert.New(t)
+		// 只接受连接，不做 tls 握手
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(err)
+		defer ln.Close()
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			time.Sleep(200 * time.Millisecond)
+			conn.Close()
+		}()
+		_, _, err = Get("https://"+ln.Addr().String()+"/").
+			SetClient(&http.Client{
+				Transport: &http.Transport{},
+			}).
+			Timeouts(0, 20*time.Millisecond, 0, 0).
+			Do()
+		assert.NotNil(err)
+		assert.True(strings.Contains(err.Error(), "TLS handshake timeout"))
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-header" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/slow-body" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	t.Run("response header timeout", func(t *testing.T) {
+		assert := assert.New(t)
+		_, _, err := Get(ts.URL+"/slow-header").
+			SetClient(&http.Client{
+				Transport: &http.Transport{},
+			}).
+			Timeouts(time.Second, 0, 20*time.Millisecond, 0).
+			Do()
+		assert.True(isTimeout(err))
+
+		_, body, err := Get(ts.URL+"/slow-body").
+			SetClient(&http.Client{
+				Transport: &http.Transport{},
+			}).
+			Timeouts(time.Second, 0, 20*time.Millisecond, 0).
+			Do()
+		assert.Nil(err)
+		assert.Equal("done", string(body))
+	})
+
+	t.Run("total timeout", func(t *testing.T) {
+		assert := assert.New(t)
+		_, _, err := Get(ts.URL+"/slow-body").
+			SetClient(&http.Client{
+				Transport: &http.Transport{},
+			}).
+			Timeouts(0, 0, 0, 20*time.Millisecond).
+			Do()
+		assert.NotNil(err)
+	})
+}
+
+func TestLocalAddr(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+	defer ts.Close()
+
+	t.Run("bind local addr", func(t *testing.T) {
+		assert := assert.New(t)
+		d := Get(ts.URL).
+			SetClient(&http.Client{
+				Transport: &http.Transport{},
+			}).
+			Timeouts(time.Second, 0, 0, 0).
+			LocalAddr("127.0.0.1").
+			EnableTrace()
+		_, body, err := d.Do()
+		assert.Nil(err)
+		assert.True(strings.HasPrefix(string(body), "127.0.0.1:"))
+		assert.Equal(string(body), d.GetHTTPTrace().LocalAddr)
+		assert.Equal(time.Second, d.dialer.Timeout)
+	})
+
+	t.Run("invalid local addr", func(t *testing.T) {
+		assert := assert.New(t)
+		_, _, err := Get(ts.URL).
+			LocalAddr("a.b.c.d").
+			Do()
+		assert.Equal(ErrLocalAddrInvalid, err)
+
+		// 192.0.2.0/24 为文档保留地址
+		_, _, err = Get(ts.URL).
+			LocalAddr("192.0.2.1").
+			Do()
+		assert.Equal(ErrLocalAddrNotFound, err)
+	})
+
+	t.Run("bind error", func(t *testing.T) {
+		assert := assert.New(t)
+		d := Get(ts.URL).
+			SetClient(&http.Client{
+				Transport: &http.Transport{},
+			})
+		d.getDialer().LocalAddr = &net.TCPAddr{
+			IP: net.ParseIP("192.0.2.1"),
+		}
+		_, _, err := d.Do()
+		localAddrErr := &LocalAddrError{}
+		assert.True(errors.As(err, &localAddrErr))
+		assert.Equal("192.0.2.1:0", localAddrErr.Addr)
+	})
+
+	t.Run("instance config", func(t *testing.T) {
+		assert := assert.New(t)
+		ins := NewInstanceWithConfig(Config{
+			LocalAddr: "127.0.0.1",
+		})
+		d := ins.Get(ts.URL)
+		assert.Equal("127.0.0.1:0", d.transportCache.dialer.LocalAddr.String())
+
+		// 所有请求共用调整后的 transport，连接可复用
+		reused := make([]bool, 0)
+		for i := 0; i < 2; i++ {
+			d := ins.Get(ts.URL).EnableTrace()
+			_, body, err := d.Do()
+			assert.Nil(err)
+			assert.True(strings.HasPrefix(string(body), "127.0.0.1:"))
+			reused = append(reused, d.GetHTTPTrace().Reused)
+		}
+		assert.Equal([]bool{false, true}, reused)
+
+		// 请求单独调整时保留 instance 的 local addr
+		d = ins.Get(ts.URL).Timeouts(time.Second, 0, 0, 0)
+		assert.Equal("127.0.0.1:0", d.dialer.LocalAddr.String())
+		_, body, err := d.Do()
+		assert.Nil(err)
+		assert.True(strings.HasPrefix(string(body), "127.0.0.1:"))
+
+		// 修改配置后重新创建 transport
+		tc := d.transportCache
+		ins.SetConfig(Config{
+			LocalAddr: "127.0.0.1",
+		})
+		assert.NotEqual(tc, ins.Get(ts.URL).transportCache)
+
+		_, _, err = NewInstanceWithConfig(Config{
+			LocalAddr: "a.b.c.d",
+		}).Get(ts.URL).Do()
+		assert.Equal(ErrLocalAddrInvalid, err)
+	})
+}
+
+// startSocks5Server start a socks5 server, the username/password authentication
+// is required if username is not empty,
+// it only supports connect command with domain or ipv4 address
+func startSocks5Server(t *testing.T, username, password string) (ln net.Listener, connects *[]string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make([]string, 0)
+	mu := sync.Mutex{}
+	handle := func(conn net.Conn) {
+		defer conn.Close()
+		buf := make([]byte, 256)
+		// 版本与认证方式
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+			return
+		}
+		if username != "" {
+			conn.Write([]byte{5, 2})
+			// 认证：版本、用户名、密码
+			if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+				return
+			}
+			size := int(buf[1])
+			if _, err := io.ReadFull(conn, buf[:size]); err != nil {
+				return
+			}
+			user := string(buf[:size])
+			if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+				return
+			}
+			size = int(buf[0])
+			if _, err := io.ReadFull(conn, buf[:size]); err != nil {
+				return
+			}
+			if user != username || string(buf[:size]) != password {
+				conn.Write([]byte{1, 1})
+				return
+			}
+			conn.Write([]byte{1, 0})
+		} else {
+			conn.Write([]byte{5, 0})
+		}
+		// 请求：版本、命令、保留、地址类型
+		if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+			return
+		}
+		host := ""
+		switch buf[3] {
+		case 1:
+			io.ReadFull(conn, buf[:4])
+			host = net.IP(buf[:4]).String()
+		case 3:
+			io.ReadFull(conn, buf[:1])
+			size := int(buf[0])
+			io.ReadFull(conn, buf[:size])
+			host = string(buf[:size])
+		default:
+			return
+		}
+		io.ReadFull(conn, buf[:2])
+		target := net.JoinHostPort(host, strconv.Itoa(int(buf[0])<<8|int(buf[1])))
+		mu.Lock()
+		result = append(result, target)
+		mu.Unlock()
+		dst, err := net.Dial("tcp", target)
+		if err != nil {
+			conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer dst.Close()
+		conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		go io.Copy(dst, conn)
+		io.Copy(conn, dst)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+	return ln, &result
+}
+
+func TestProxy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("origin"))
+	}))
+	defer ts.Close()
+
+	t.Run("http proxy", func(t *testing.T) {
+		assert := assert.New(t)
+		requestURLs := make([]string, 0)
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// 经代理的请求 url 为完整地址
+			requestURLs = append(requestURLs, r.URL.String())
+			w.Write([]byte("proxy"))
+		}))
+		defer proxy.Close()
+
+		_, body, err := Get("http://aslant.site/users").
+			Proxy(proxy.URL).
+			Do()
+		assert.Nil(err)
+		assert.Equal("proxy", string(body))
+		assert.Equal([]string{
+			"http://aslant.site/users",
+		}, requestURLs)
+
+		// 不影响其它请求
+		_, body, err = Get(ts.URL).Do()
+		assert.Nil(err)
+		assert.Equal("origin", string(body))
+	})
+
+	t.Run("socks5 proxy", func(t *testing.T) {
+		assert := assert.New(t)
+		ln, connects := startSocks5Server(t, "", "")
+		defer ln.Close()
+		_, body, err := Get(ts.URL).
+			Proxy("socks5://" + ln.Addr().String()).
+			Do()
+		assert.Nil(err)
+		assert.Equal("origin", string(body))
+		assert.Equal([]string{
+			strings.TrimPrefix(ts.URL, "http://"),
+		}, *connects)
+	})
+
+	t.Run("invalid proxy url", func(t *testing.T) {
+		assert := assert.New(t)
+		for _, proxyURL := range []string{
+			"://127.0.0.1",
+			"ftp://127.0.0.1:21",
+			"http://",
+		} {
+			_, _, err := Get(ts.URL).
+				Proxy(proxyURL).
+				Do()
+			assert.True(errors.Is(err, ErrProxyInvalid), proxyURL)
+		}
+	})
+}
+
+func TestSetSOCKS5Proxy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("origin"))
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	t.Run("no authentication", func(t *testing.T) {
+		assert := assert.New(t)
+		ln, connects := startSocks5Server(t, "", "")
+		defer ln.Close()
+		_, body, err := Get(ts.URL).
+			SetSOCKS5Proxy(ln.Addr().String(), "", "").
+			Do()
+		assert.Nil(err)
+		assert.Equal("origin", string(body))
+		assert.Equal([]string{host}, *connects)
+	})
+
+	t.Run("instance with authentication", func(t *testing.T) {
+		assert := assert.New(t)
+		ln, connects := startSocks5Server(t, "tree", "xie")
+		defer ln.Close()
+		ins := NewInstance().SetSOCKS5Proxy(ln.Addr().String(), "tree", "xie")
+		for i := 0; i < 2; i++ {
+			_, body, err := ins.Get(ts.URL).Do()
+			assert.Nil(err)
+			assert.Equal("origin", string(body))
+		}
+		// instance 的请求共用 transport，连接可复用
+		assert.Equal([]string{host}, *connects)
+
+		// 请求单独调整 dialer 时仍经代理连接
+		_, body, err := ins.Get(ts.URL).
+			Timeouts(time.Second, 0, 0, 0).
+			Do()
+		assert.Nil(err)
+		assert.Equal("origin", string(body))
+		assert.Equal([]string{host, host}, *connects)
+
+		_, _, err = Get(ts.URL).
+			SetSOCKS5Proxy(ln.Addr().String(), "tree", "invalid").
+			Do()
+		assert.NotNil(err)
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		assert := assert.New(t)
+		_, _, err := Get(ts.URL).
+			SetSOCKS5Proxy("127.0.0.1", "", "").
+			Do()
+		assert.True(errors.Is(err, ErrProxyInvalid))
+
+		_, _, err = NewInstance().
+			SetSOCKS5Proxy("127.0.0.1", "", "").
+			Get(ts.URL).
+			Do()
+		assert.True(errors.Is(err, ErrProxyInvalid))
+	})
+}
+
+func TestSetHTTPProxy(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("origin"))
+	}))
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "https://")
+
+	var mu sync.Mutex
+	connects := make([]string, 0)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		mu.Lock()
+		connects = append(connects, r.Host)
+		mu.Unlock()
+		dst, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer dst.Close()
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go io.Copy(dst, rw)
+		io.Copy(conn, dst)
+	}))
+	defer proxy.Close()
+
+	t.Run("connect tunnel", func(t *testing.T) {
+		assert := assert.New(t)
+		_, body, err := Get(ts.URL).
+			SetHTTPProxy(proxy.URL).
+			InsecureSkipVerify().
+			Do()
+		assert.Nil(err)
+		assert.Equal("origin", string(body))
+
+		// instance 的请求共用 transport，隧道连接可复用
+		ins := NewInstance().SetHTTPProxy(proxy.URL)
+		client := ts.Client()
+		for i := 0; i < 2; i++ {
+			_, body, err = ins.Get(ts.URL).
+				SetClient(client).
+				Do()
+			assert.Nil(err)
+			assert.Equal("origin", string(body))
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal([]string{host, host}, connects)
+	})
+
+	t.Run("invalid proxy url", func(t *testing.T) {
+		assert := assert.New(t)
+		for _, proxyURL := range []string{
+			"socks5://127.0.0.1:1080",
+			"http://",
+		} {
+			_, _, err := Get(ts.URL).
+				SetHTTPProxy(proxyURL).
+				Do()
+			assert.True(errors.Is(err, ErrProxyInvalid), proxyURL)
+
+			_, _, err = NewInstance().
+				SetHTTPProxy(proxyURL).
+				Get(ts.URL).
+				Do()
+			assert.True(errors.Is(err, ErrProxyInvalid), proxyURL)
+		}
+	})
+
+	t.Run("proxy from environment", func(t *testing.T) {
+		assert := assert.New(t)
+		ins := NewInstance().SetProxyFromEnvironment()
+		client := &http.Client{
+			Transport: &http.Transport{},
+		}
+		transport, err := ins.Get(ts.URL).getBaseTransport(client)
+		assert.Nil(err)
+		assert.NotNil(transport.Proxy)
+		// 相同的 transport 仅复制一次
+		cached, err := ins.Get(ts.URL).getBaseTransport(client)
+		assert.Nil(err)
+		assert.True(transport == cached)
+	})
+}
+
+func TestTLSConfig(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	// 证书校验失败的握手出错不输出
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	t.Run("unknown authority", func(t *testing.T) {
+		assert := assert.New(t)
+		_, _, err := Get(ts.URL).Do()
+		var unknownAuthorityErr x509.UnknownAuthorityError
+		assert.True(errors.As(err, &unknownAuthorityErr))
+	})
+
+	t.Run("root cas", func(t *testing.T) {
+		assert := assert.New(t)
+		pool := x509.NewCertPool()
+		pool.AddCert(ts.Certificate())
+		cfg := &tls.Config{
+			RootCAs: pool,
+		}
+		d := Get(ts.URL).TLSConfig(cfg)
+		_, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal("ok", string(body))
+		c, err := d.getRequestClient()
+		assert.Nil(err)
+		tlsConfig := c.Transport.(*http.Transport).TLSClientConfig
+		// 使用复制的配置
+		assert.False(cfg == tlsConfig)
+		assert.Equal(pool, tlsConfig.RootCAs)
+		// 共享的 transport 不受影响
+		if defaultTLSConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig; defaultTLSConfig != nil {
+			assert.Nil(defaultTLSConfig.RootCAs)
+		}
+	})
+
+	t.Run("insecure skip verify", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := &tls.Config{
+			ServerName: "example.com",
+		}
+		d := Get(ts.URL).
+			TLSConfig(cfg).
+			InsecureSkipVerify()
+		_, body, err := d.Do()
+		assert.Nil(err)
+		assert.Equal("ok", string(body))
+		c, err := d.getRequestClient()
+		assert.Nil(err)
+		tlsConfig := c.Transport.(*http.Transport).TLSClientConfig
+		assert.True(tlsConfig.InsecureSkipVerify)
+		assert.Equal("example.com", tlsConfig.ServerName)
+		assert.False(cfg.InsecureSkipVerify)
+	})
+}
+
+// newClientCertPEM create a self-signed client certificate
+func newClientCertPEM(t *testing.T) (certPEM, keyPEM []byte, cert *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: "dusk",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: der,
+	})
+	keyPEM = pem.EncodeToMemory(&pem.Block{
+		Type:  "EC PRIVATE KEY",
+		Bytes: keyDER,
+	})
+	return
+}
+
+func TestClientCert(t *testing.T) {
+	certPEM, keyPEM, cert := newClientCertPEM(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	var hits int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	t.Run("pem", func(t *testing.T) {
+		assert := assert.New(t)
+		// 证书在 TLSConfig 之前设置也不会被覆盖
+		_, body, err := Get(ts.URL).
+			ClientCertPEM(certPEM, keyPEM).
+			TLSConfig(&tls.Config{
+				InsecureSkipVerify: true,
+			}).
+			Do()
+		assert.Nil(err)
+		assert.Equal("dusk", string(body))
+	})
+
+	t.Run("file", func(t *testing.T) {
+		assert := assert.New(t)
+		dir, err := ioutil.TempDir("", "dusk")
+		assert.Nil(err)
+		defer os.RemoveAll(dir)
+		certFile := filepath.Join(dir, "cert.pem")
+		keyFile := filepath.Join(dir, "key.pem")
+		assert.Nil(ioutil.WriteFile(certFile, certPEM, 0600))
+		assert.Nil(ioutil.WriteFile(keyFile, keyPEM, 0600))
+
+		_, body, err := Get(ts.URL).
+			InsecureSkipVerify().
+			ClientCert(certFile, keyFile).
+			Do()
+		assert.Nil(err)
+		assert.Equal("dusk", string(body))
+	})
+
+	t.Run("no client cert", func(t *testing.T) {
+		assert := assert.New(t)
+		count := atomic.LoadInt32(&hits)
+		_, _, err := Get(ts.URL).
+			InsecureSkipVerify().
+			Do()
+		assert.NotNil(err)
+		assert.Equal(count, atomic.LoadInt32(&hits))
+	})
+
+	t.Run("invalid cert", func(t *testing.T) {
+		assert := assert.New(t)
+		count := atomic.LoadInt32(&hits)
+		_, _, err := Get(ts.URL).
+			InsecureSkipVerify().
+			ClientCertPEM(certPEM, []byte("abcd")).
+			Do()
+		assert.NotNil(err)
+		_, _, err = Get(ts.URL).
+			InsecureSkipVerify().
+			ClientCert("cert-not-found.pem", "key-not-found.pem").
+			Do()
+		assert.True(errors.Is(err, os.ErrNotExist))
+		assert.Equal(count, atomic.LoadInt32(&hits))
+	})
+}